@@ -0,0 +1,219 @@
+package apiclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/Enclave-Markets/enclave-go/models"
+)
+
+const defaultBatchConcurrency = 4
+
+// WithBatchConcurrency bounds how many requests a batch call sends at once on servers without a batch endpoint.
+// Defaults to 4. Every request still waits on the client's rate limits, and a batch request takes one token per order
+// from its bucket, the same as sending the orders one by one.
+func WithBatchConcurrency(n int) Option {
+	return func(cfg *clientConfig) {
+		cfg.batchConcurrency = n
+	}
+}
+
+// BatchResult is the outcome of one item of a batch call, at the index of the item in the call.
+type BatchResult struct {
+	// The placed order, or the canceled one when the server returns it.
+	Order *models.ApiOrder
+
+	// Why the item failed. It matches the same sentinel errors as a single request, e.g. ErrOrderNotFound.
+	Err error
+}
+
+func (client *ApiClient) AddSpotOrders(reqs []models.AddOrderReq) ([]BatchResult, error) {
+	return client.AddSpotOrdersCtx(context.Background(), reqs)
+}
+
+// AddSpotOrdersCtx places several orders, in one request when the server has a batch endpoint and otherwise with a
+// bounded fan-out of AddSpotOrderCtx. Items fail independently; the returned error is only set when the batch as a
+// whole could not be sent.
+func (client *ApiClient) AddSpotOrdersCtx(ctx context.Context, reqs []models.AddOrderReq) ([]BatchResult, error) {
+	results := make([]BatchResult, len(reqs))
+
+	// Gated orders fail on their own without being sent
+	client.mu.RLock()
+	gate := client.marketGate
+	client.mu.RUnlock()
+	var pending []int
+	for i, req := range reqs {
+		if gate != nil {
+			if err := gate.CheckOrder(req); err != nil {
+				results[i].Err = err
+				continue
+			}
+		}
+		pending = append(pending, i)
+	}
+	if len(pending) == 0 {
+		return results, nil
+	}
+
+	if !client.batchUnsupported.Load() {
+		batch := models.BatchAddOrdersReq{Orders: make([]models.AddOrderReq, len(pending))}
+		for j, i := range pending {
+			batch.Orders[j] = reqs[i]
+		}
+
+		path := models.V1SpotOrdersBatchPath
+		res, err := newJsonClient[models.BatchAddOrdersReq, models.GenericResponse[[]models.BatchOrderResult]](client, path).
+			WithHeaderFunc(client.signCall).WithRateLimitCost(len(pending)).PostCtx(ctx, batch)
+
+		if err == nil {
+			return results, mergeBatchResults(results, pending, res, "POST", path)
+		}
+		if !isMissingEndpoint(err) {
+			return nil, fmt.Errorf("error in http req spot batch add orders: %w", err)
+		}
+		client.batchUnsupported.Store(true)
+	}
+
+	client.fanOut(pending, func(i int) {
+		res, err := client.AddSpotOrderCtx(ctx, reqs[i])
+		if err != nil {
+			results[i].Err = err
+			return
+		}
+		results[i].Order = &res.Result
+	})
+	return results, nil
+}
+
+func (client *ApiClient) CancelSpotOrders(orders []models.OrderRef) ([]BatchResult, error) {
+	return client.CancelSpotOrdersCtx(context.Background(), orders)
+}
+
+// CancelSpotOrdersCtx cancels several orders, each addressed by its exchange OrderID or its ClientOrderID, see
+// AddSpotOrdersCtx for how the batch is sent.
+func (client *ApiClient) CancelSpotOrdersCtx(ctx context.Context, orders []models.OrderRef) ([]BatchResult, error) {
+	results := make([]BatchResult, len(orders))
+
+	// The batch lists exchange IDs then client IDs, its results come back in that order
+	var batch models.BatchCancelOrdersReq
+	var exchangeIndexes, clientIndexes []int
+	for i, order := range orders {
+		switch id := order.(type) {
+		case models.OrderID:
+			batch.OrderIDs = append(batch.OrderIDs, id)
+			exchangeIndexes = append(exchangeIndexes, i)
+		case models.ClientOrderID:
+			batch.ClientOrderIDs = append(batch.ClientOrderIDs, models.OrderID(id))
+			clientIndexes = append(clientIndexes, i)
+		default:
+			results[i].Err = fmt.Errorf("unsupported order ref %T", order)
+		}
+	}
+	pending := append(exchangeIndexes, clientIndexes...)
+	if len(pending) == 0 {
+		return results, nil
+	}
+
+	if !client.batchUnsupported.Load() {
+		path := models.V1SpotOrdersBatchPath
+		res, err := newJsonClient[models.BatchCancelOrdersReq, models.GenericResponse[[]models.BatchOrderResult]](client, path).
+			WithHeaderFunc(client.signCall).WithRateLimitCost(len(pending)).DeleteCtx(ctx, batch)
+
+		if err == nil && !res.Success {
+			err = newResponseError("DELETE", path, res.Error)
+		}
+		if err == nil {
+			return results, mergeBatchResults(results, pending, res, "DELETE", path)
+		}
+		if !isMissingBatchCancel(err) {
+			return nil, fmt.Errorf("error in http req spot batch cancel orders: %w", err)
+		}
+		client.batchUnsupported.Store(true)
+	}
+
+	client.fanOut(pending, func(i int) {
+		if _, err := client.CancelSpotOrderCtx(ctx, orders[i]); err != nil {
+			results[i].Err = err
+		}
+	})
+	return results, nil
+}
+
+func (client *ApiClient) CancelSpotOrdersByClientID(clientOrderIds []models.OrderID) ([]BatchResult, error) {
+	return client.CancelSpotOrdersByClientIDCtx(context.Background(), clientOrderIds)
+}
+
+func (client *ApiClient) CancelSpotOrdersByClientIDCtx(ctx context.Context, clientOrderIds []models.OrderID) ([]BatchResult, error) {
+	orders := make([]models.OrderRef, len(clientOrderIds))
+	for i, id := range clientOrderIds {
+		orders[i] = models.ClientOrderID(id)
+	}
+	return client.CancelSpotOrdersCtx(ctx, orders)
+}
+
+// isMissingBatchCancel reports whether err is a server without the batch endpoint answering a batch cancel. The batch
+// path also matches the single cancel route /v1/orders/{id}, so such a server answers it as the cancel of an order
+// with id "batch", e.g. order not found or a bad request, rather than with a bare 404. A batch that was rejected as a
+// whole for the credentials or the rate limit would fail the same way one by one, and is not retried.
+func isMissingBatchCancel(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if errors.Is(apiErr, ErrUnauthorized) || errors.Is(apiErr, ErrRateLimited) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusOK ||
+		(apiErr.StatusCode >= http.StatusBadRequest && apiErr.StatusCode < http.StatusInternalServerError)
+}
+
+// mergeBatchResults stores the items of a batch response, sent for the items at indexes, in results.
+func mergeBatchResults(
+	results []BatchResult,
+	indexes []int,
+	res *models.GenericResponse[[]models.BatchOrderResult],
+	method string,
+	path string,
+) error {
+	if !res.Success {
+		return fmt.Errorf("bad request spot batch: %w", newResponseError(method, path, res.Error))
+	}
+	if len(res.Result) != len(indexes) {
+		return fmt.Errorf("spot batch returned %d results for %d items", len(res.Result), len(indexes))
+	}
+
+	for j, i := range indexes {
+		item := res.Result[j]
+		results[i].Order = item.Order
+		if item.Error != "" {
+			results[i].Err = newResponseError(method, path, item.Error)
+		}
+	}
+	return nil
+}
+
+// fanOut calls do for every index, with at most the client's batch concurrency running at once.
+func (client *ApiClient) fanOut(indexes []int, do func(i int)) {
+	concurrency := client.batchConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, i := range indexes {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			do(i)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,317 @@
+package apiclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Enclave-Markets/enclave-go/models"
+	"github.com/shopspring/decimal"
+)
+
+// fakeExchange serves the single order routes, POST and GET /v1/orders and GET and DELETE /v1/orders/{id}, from
+// an in-memory order set. Any other route answers a bare 404 unless route handles it.
+type fakeExchange struct {
+	mu     sync.Mutex
+	orders map[models.OrderID]*models.ApiOrder
+	nextID int
+
+	// Requests received, as "METHOD /path?query".
+	requests []string
+
+	// Status of the reply to a request for an order that does not exist. Zero means http.StatusNotFound.
+	notFoundStatus int
+
+	// Called first, it returns true when it answered the request.
+	route func(w http.ResponseWriter, r *http.Request) bool
+}
+
+func newFakeExchange(t *testing.T) (*fakeExchange, *httptest.Server) {
+	t.Helper()
+
+	exchange := &fakeExchange{orders: map[models.OrderID]*models.ApiOrder{}}
+	server := httptest.NewServer(exchange)
+	t.Cleanup(server.Close)
+	return exchange, server
+}
+
+func (f *fakeExchange) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.RequestURI())
+	route := f.route
+	f.mu.Unlock()
+
+	if route != nil && route(w, r) {
+		return
+	}
+
+	if r.URL.Path == models.V1SpotOrdersPath {
+		switch r.Method {
+		case http.MethodPost:
+			var req models.AddOrderReq
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			writeResult(w, f.add(req))
+		case http.MethodGet:
+			f.list(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+		return
+	}
+
+	segment, ok := strings.CutPrefix(r.URL.Path, models.V1SpotOrdersPath+"/")
+	if !ok || strings.Contains(segment, "/") || (r.Method != http.MethodGet && r.Method != http.MethodDelete) {
+		http.NotFound(w, r)
+		return
+	}
+
+	order, ok := f.order(segment)
+	if !ok {
+		status := f.notFoundStatus
+		if status == 0 {
+			status = http.StatusNotFound
+		}
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, `{"success":false,"error":"order not found"}`)
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		writeResult(w, order)
+		return
+	}
+	f.cancel(order.OrderID)
+	_, _ = io.WriteString(w, `{"success":true}`)
+}
+
+func (f *fakeExchange) add(req models.AddOrderReq) models.ApiOrder {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.nextID++
+	order := &models.ApiOrder{
+		OrderID:       models.OrderID(fmt.Sprintf("o%d", f.nextID)),
+		ClientOrderID: req.ClientOrderID,
+		Side:          req.Side,
+		Price:         req.Price,
+		OrderQuantity: req.Size,
+		Market:        req.Market,
+		State:         models.Open,
+		CreatedAt:     time.Now(),
+		Type:          req.Type,
+	}
+	f.orders[order.OrderID] = order
+	return *order
+}
+
+// order returns the order addressed by a path segment, an exchange ID or a client ID.
+func (f *fakeExchange) order(segment string) (models.ApiOrder, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if clientID, ok := strings.CutPrefix(segment, models.V1SpotClientOrderIDPrefix); ok {
+		for _, order := range f.orders {
+			if string(order.ClientOrderID) == clientID {
+				return *order, true
+			}
+		}
+		return models.ApiOrder{}, false
+	}
+	order, ok := f.orders[models.OrderID(segment)]
+	if !ok {
+		return models.ApiOrder{}, false
+	}
+	return *order, true
+}
+
+func (f *fakeExchange) cancel(id models.OrderID) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	order := f.orders[id]
+	if order.State == models.Open {
+		now := time.Now()
+		order.State = models.Canceled
+		order.CanceledAt = &now
+	}
+}
+
+// list answers one page holding the orders matching the status, market and side query parameters.
+func (f *fakeExchange) list(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	f.mu.Lock()
+	res := models.V1PageRes[models.ApiOrder]{Result: []*models.ApiOrder{}}
+	for _, order := range f.orders {
+		if status := query.Get("status"); status != "" && order.State.String() != status {
+			continue
+		}
+		if market := query.Get("market"); market != "" && string(order.Market) != market {
+			continue
+		}
+		if side := query.Get("side"); side != "" && order.Side.String() != side {
+			continue
+		}
+		listed := *order
+		res.Result = append(res.Result, &listed)
+	}
+	f.mu.Unlock()
+
+	_ = json.NewEncoder(w).Encode(res)
+}
+
+func (f *fakeExchange) state(id models.OrderID) models.OrderState {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.orders[id].State
+}
+
+func (f *fakeExchange) received(method string, path string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n := 0
+	for _, request := range f.requests {
+		if request == method+" "+path {
+			n++
+		}
+	}
+	return n
+}
+
+func writeResult(w http.ResponseWriter, result any) {
+	_ = json.NewEncoder(w).Encode(models.GenericResponse[any]{Success: true, Result: result})
+}
+
+func limitOrder(market models.Market, side models.BidAsk, clientOrderID models.OrderID) models.AddOrderReq {
+	return models.AddOrderReq{
+		Side:          side,
+		Price:         decimal.NewFromInt(10),
+		Size:          decimal.NewFromInt(1),
+		Market:        market,
+		ClientOrderID: clientOrderID,
+		Type:          models.OrderTypeLimit,
+	}
+}
+
+// TestBatchWithoutBatchEndpoint runs batch calls against a server with only the single order routes, which answers
+// POST /v1/orders/batch with a bare 404 and DELETE /v1/orders/batch as the cancel of an unknown order with id "batch".
+func TestBatchWithoutBatchEndpoint(t *testing.T) {
+	t.Run("add", func(t *testing.T) {
+		exchange, server := newFakeExchange(t)
+		client := NewApiClient(server.URL)
+
+		placed, err := client.AddSpotOrders([]models.AddOrderReq{
+			limitOrder("AVAX-USDC", models.Bid, "a"),
+			limitOrder("AVAX-USDC", models.Ask, "b"),
+		})
+		if err != nil {
+			t.Fatalf("add orders: %v", err)
+		}
+		for i, result := range placed {
+			if result.Err != nil || result.Order == nil {
+				t.Fatalf("order %d not placed: %+v", i, result)
+			}
+			if state := exchange.state(result.Order.OrderID); state != models.Open {
+				t.Fatalf("order %s is %s after add", result.Order.OrderID, state)
+			}
+		}
+	})
+
+	for _, notFoundStatus := range []int{http.StatusNotFound, http.StatusOK} {
+		t.Run(fmt.Sprintf("cancel with not found status %d", notFoundStatus), func(t *testing.T) {
+			exchange, server := newFakeExchange(t)
+			exchange.notFoundStatus = notFoundStatus
+			client := NewApiClient(server.URL)
+
+			a := exchange.add(limitOrder("AVAX-USDC", models.Bid, "a"))
+			b := exchange.add(limitOrder("AVAX-USDC", models.Ask, "b"))
+
+			results, err := client.CancelSpotOrders([]models.OrderRef{
+				a.OrderID,
+				models.ClientOrderID("b"),
+				models.OrderID("unknown"),
+			})
+			if err != nil {
+				t.Fatalf("cancel orders: %v", err)
+			}
+			if results[0].Err != nil || results[1].Err != nil {
+				t.Fatalf("cancels failed: %v, %v", results[0].Err, results[1].Err)
+			}
+			if !errors.Is(results[2].Err, ErrOrderNotFound) {
+				t.Fatalf("unknown order: got %v, want ErrOrderNotFound", results[2].Err)
+			}
+			for _, order := range []models.ApiOrder{a, b} {
+				if state := exchange.state(order.OrderID); state != models.Canceled {
+					t.Fatalf("order %s is %s after cancel", order.OrderID, state)
+				}
+			}
+
+			// The missing endpoint is remembered
+			if _, err := client.CancelSpotOrders([]models.OrderRef{a.OrderID}); err != nil {
+				t.Fatalf("second cancel: %v", err)
+			}
+			if n := exchange.received(http.MethodDelete, models.V1SpotOrdersBatchPath); n != 1 {
+				t.Fatalf("sent %d batch cancels, want 1", n)
+			}
+		})
+	}
+}
+
+func TestCancelSpotOrdersBatch(t *testing.T) {
+	exchange, server := newFakeExchange(t)
+	var got models.BatchCancelOrdersReq
+	exchange.route = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method != http.MethodDelete || r.URL.Path != models.V1SpotOrdersBatchPath {
+			return false
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return true
+		}
+		// Exchange IDs then client IDs
+		writeResult(w, []models.BatchOrderResult{
+			{Order: &models.ApiOrder{OrderID: "o1"}},
+			{Error: "order not found"},
+			{Order: &models.ApiOrder{OrderID: "o3", ClientOrderID: "c"}},
+		})
+		return true
+	}
+	client := NewApiClient(server.URL)
+
+	results, err := client.CancelSpotOrders([]models.OrderRef{
+		models.OrderID("o1"),
+		models.ClientOrderID("c"),
+		models.OrderID("o2"),
+	})
+	if err != nil {
+		t.Fatalf("cancel orders: %v", err)
+	}
+
+	if len(got.OrderIDs) != 2 || got.OrderIDs[0] != "o1" || got.OrderIDs[1] != "o2" ||
+		len(got.ClientOrderIDs) != 1 || got.ClientOrderIDs[0] != "c" {
+		t.Fatalf("unexpected batch request %+v", got)
+	}
+	if results[0].Err != nil || results[0].Order.OrderID != "o1" {
+		t.Fatalf("result 0: %+v", results[0])
+	}
+	if results[1].Err != nil || results[1].Order.OrderID != "o3" {
+		t.Fatalf("result 1: %+v", results[1])
+	}
+	if !errors.Is(results[2].Err, ErrOrderNotFound) {
+		t.Fatalf("result 2: got %v, want ErrOrderNotFound", results[2].Err)
+	}
+	if n := len(exchange.requests); n != 1 {
+		t.Fatalf("sent %d requests, want the batch only", n)
+	}
+}
@@ -1,12 +1,15 @@
 package apiclient
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/url"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/Enclave-Markets/enclave-go/models"
@@ -19,70 +22,172 @@ type ApiKeyArgs struct {
 	Sign      string
 }
 
+// ApiClient is safe for concurrent use by multiple goroutines once built. Each request is signed with its own
+// timestamp and signature, and the API key and base headers may be changed while requests are in flight through
+// WithApiKey, WithSigner, SetHeader and DelHeader.
 type ApiClient struct {
 	ApiEndpoint string
 
+	// Guards signer, Headers, clock and marketGate.
+	mu sync.RWMutex
+
 	// Can be used to authenticate requests. Either with JWT token or an API key. The API key needs to sign
 	// each request with a timestamp and signature.
-	apiKeyArgs *ApiKeyArgs
-	Headers    map[string]string
+	signer Signer
+
+	// Extra headers sent with every request. The map may be filled before the client is shared, afterwards use
+	// SetHeader and DelHeader.
+	Headers map[string]string
+
+	// Used to send every request. Nil means http.DefaultClient.
+	httpClient *http.Client
+
+	// Applied to idempotent requests and to orders with a client order ID. Nil disables retries.
+	retryPolicy *RetryPolicy
+
+	// Client-side rate limits. Nil disables them.
+	rateLimiter *RateLimiter
+
+	// Run around every call, in order.
+	interceptors []Interceptor
+
+	// Offsets signing timestamps to the server clock. Nil uses the local clock.
+	clock *ClockSync
+
+	// Rejects orders to markets that do not accept them. Nil disables gating.
+	marketGate *MarketStatusWatcher
+
+	// Set once the server answered that it has no amend endpoint, replaces then cancel and place.
+	amendUnsupported atomic.Bool
+
+	// Set once the server answered that it has no batch endpoint, batches then fan out single requests.
+	batchUnsupported atomic.Bool
+
+	// Set once the server answered that it cannot cancel by market, such cancels then list and cancel the orders.
+	cancelByMarketUnsupported atomic.Bool
+
+	// Requests a batch fan-out sends at once. Zero means defaultBatchConcurrency.
+	batchConcurrency int
 }
 
 func (c *ApiClient) WithApiKey(keyId, keySecret string) {
-	c.apiKeyArgs = &ApiKeyArgs{
-		KeyId:     keyId,
-		KeySecret: keySecret,
+	c.WithSigner(NewHmacSigner(keyId, keySecret))
+}
+
+// WithSigner authenticates requests with signatures computed by signer, so the API secret does not have to be held
+// by the client.
+func (c *ApiClient) WithSigner(signer Signer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.signer = signer
+}
+
+// SetHeader sets a header sent with every request.
+func (c *ApiClient) SetHeader(key string, value string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.Headers == nil {
+		c.Headers = map[string]string{}
 	}
+	c.Headers[key] = value
+}
+
+// DelHeader removes a header set with SetHeader.
+func (c *ApiClient) DelHeader(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.Headers, key)
 }
 
-func generateSignature(apiSecret string, timestamp string, method string, requestPath string, body string) []byte {
-	concattedString := timestamp + method + requestPath + body
-	mac := hmac.New(sha256.New, []byte(apiSecret))
-	mac.Write([]byte(concattedString))
-	return mac.Sum(nil)
+// baseHeaders returns a copy of the extra headers set on the client.
+func (c *ApiClient) baseHeaders() map[string]string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	headers := make(map[string]string, len(c.Headers))
+	for k, v := range c.Headers {
+		headers[k] = v
+	}
+	return headers
 }
 
-func (c *ApiClient) computeApiKeyArgs(httpVerb string, path string, request any) {
-	if c.apiKeyArgs == nil {
-		return
+// computeApiKeyArgs returns the key id, timestamp and signature of this request, or nil if the client has no API
+// key. KeySecret is never set, the secret stays with the signer.
+func (c *ApiClient) computeApiKeyArgs(ctx context.Context, httpVerb string, path string, body string) (*ApiKeyArgs, error) {
+	c.mu.RLock()
+	signer := c.signer
+	clock := c.clock
+	c.mu.RUnlock()
+
+	if signer == nil {
+		return nil, nil
 	}
-	jsonBody, err := json.Marshal(request)
-	if err != nil {
-		panic(fmt.Sprintf("Failed to marshal request body: %s", err.Error()))
+
+	now := time.Now()
+	if clock != nil {
+		now = clock.Now()
 	}
-	body := string(jsonBody)
-	if body == "null" {
-		body = ""
+	timestamp := fmt.Sprint(now.UnixMilli())
+	sig, err := signer.Sign(ctx, []byte(timestamp+httpVerb+path+body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign request: %w", err)
 	}
 
-	c.apiKeyArgs.Timestamp = fmt.Sprint(time.Now().UnixMilli())
-	sig := generateSignature(c.apiKeyArgs.KeySecret, c.apiKeyArgs.Timestamp, httpVerb, path, body)
-	hexSig := hex.EncodeToString(sig)
-	c.apiKeyArgs.Sign = hexSig
+	return &ApiKeyArgs{
+		KeyId:     signer.KeyID(),
+		Timestamp: timestamp,
+		Sign:      hex.EncodeToString(sig),
+	}, nil
+}
+
+// signCall returns the auth headers of one attempt of a call, signing its method, path and body as they are about
+// to be sent, after the interceptors ran.
+func (c *ApiClient) signCall(ctx context.Context, call *Call) (map[string]string, error) {
+	// The signed path is relative to the api endpoint, without any base path it has
+	path := call.URL.RequestURI()
+	if endpoint, err := url.Parse(c.ApiEndpoint); err == nil {
+		path = strings.TrimPrefix(path, strings.TrimSuffix(endpoint.Path, "/"))
+	}
 
+	return c.getAuthHeaders(ctx, call.Method, path, string(call.Body))
 }
 
-// getHeaders returns the headers for a request. It includes the auth headers and any extra headers set on the client.
-func (c *ApiClient) getHeaders(httpVerb string, path string, request any) map[string]string {
-	headers := c.getAuthHeaders(httpVerb, path, request)
+// AuthHeaders returns the ENCLAVE-KEY-ID, ENCLAVE-TIMESTAMP and ENCLAVE-SIGN headers authenticating a request, e.g.
+// to log in to the websocket stream. It is empty when the client has no API key.
+func (c *ApiClient) AuthHeaders(httpVerb string, path string, request any) (map[string]string, error) {
+	return c.AuthHeadersCtx(context.Background(), httpVerb, path, request)
+}
 
-	for k, v := range c.Headers {
-		headers[k] = v
+// AuthHeadersCtx is AuthHeaders with a context bounding the signature, e.g. a call to a signing daemon.
+func (c *ApiClient) AuthHeadersCtx(ctx context.Context, httpVerb string, path string, request any) (map[string]string, error) {
+	jsonBody, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+	body := string(jsonBody)
+	if body == "null" {
+		body = ""
 	}
 
-	return headers
+	return c.getAuthHeaders(ctx, httpVerb, path, body)
 }
 
-func (c *ApiClient) getAuthHeaders(httpVerb string, path string, request any) map[string]string {
-	c.computeApiKeyArgs(httpVerb, path, request)
+func (c *ApiClient) getAuthHeaders(ctx context.Context, httpVerb string, path string, body string) (map[string]string, error) {
+	keyArgs, err := c.computeApiKeyArgs(ctx, httpVerb, path, body)
+	if err != nil {
+		return nil, err
+	}
 	headers := map[string]string{}
-	if c.apiKeyArgs != nil {
-		headers["ENCLAVE-KEY-ID"] = c.apiKeyArgs.KeyId
-		headers["ENCLAVE-TIMESTAMP"] = c.apiKeyArgs.Timestamp
-		headers["ENCLAVE-SIGN"] = c.apiKeyArgs.Sign
+	if keyArgs != nil {
+		headers["ENCLAVE-KEY-ID"] = keyArgs.KeyId
+		headers["ENCLAVE-TIMESTAMP"] = keyArgs.Timestamp
+		headers["ENCLAVE-SIGN"] = keyArgs.Sign
 	}
 
-	return headers
+	return headers, nil
 }
 
 func NewApiClient(apiEndpoint string) *ApiClient {
@@ -93,14 +198,9 @@ func NewApiClient(apiEndpoint string) *ApiClient {
 }
 
 func NewApiClientFromEnv(env string) (*ApiClient, error) {
-	var apiUrl string
-	switch strings.ToLower(env) {
-	case "sandbox":
-		apiUrl = "https://api-sandbox.enclave.market"
-	case "prod":
-		apiUrl = "https://api.enclave.market"
-	default:
-		return nil, fmt.Errorf("unknown env: %s", env)
+	apiUrl, err := envEndpoint(env)
+	if err != nil {
+		return nil, err
 	}
 
 	return &ApiClient{
@@ -109,22 +209,61 @@ func NewApiClientFromEnv(env string) (*ApiClient, error) {
 	}, nil
 }
 
+// RateLimiter returns the client-side rate limiter, or nil if the client was built without WithRateLimits.
+func (client *ApiClient) RateLimiter() *RateLimiter {
+	return client.rateLimiter
+}
+
+func envEndpoint(env string) (string, error) {
+	switch strings.ToLower(env) {
+	case "sandbox":
+		return "https://api-sandbox.enclave.market", nil
+	case "prod":
+		return "https://api.enclave.market", nil
+	default:
+		return "", fmt.Errorf("unknown env: %s", env)
+	}
+}
+
+// newJsonClient returns an HttpJsonClient for path on the api endpoint that sends through the client's transport,
+// retry policy, rate limiter and interceptors with the client's base headers.
+func newJsonClient[REQUEST_T any, REPLY_T any](client *ApiClient, path string) *HttpJsonClient[REQUEST_T, REPLY_T] {
+	return NewHttpJsonClient[REQUEST_T, REPLY_T](client.ApiEndpoint + path).
+		WithHttpClient(client.httpClient).
+		WithRetryPolicy(client.retryPolicy).
+		WithRateLimiter(client.rateLimiter).
+		WithInterceptors(client.interceptors...).
+		SetHeaders(client.baseHeaders())
+}
+
 func (client *ApiClient) WaitForEndpoint() {
+	_ = client.WaitForEndpointCtx(context.Background())
+}
+
+// WaitForEndpointCtx polls the status endpoint until it answers. It returns the context error if the context is
+// done first.
+func (client *ApiClient) WaitForEndpointCtx(ctx context.Context) error {
 	for {
-		if _, err := client.GetPublicStatus(); err != nil {
-			time.Sleep(2 * time.Second)
-			continue
+		if _, err := client.GetPublicStatusCtx(ctx); err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(2 * time.Second):
 		}
-		return
 	}
 }
 
 func (client *ApiClient) GetPublicStatus() (*models.GetPublicStatusRes, error) {
+	return client.GetPublicStatusCtx(context.Background())
+}
+
+func (client *ApiClient) GetPublicStatusCtx(ctx context.Context) (*models.GetPublicStatusRes, error) {
 	path := models.StatusPath
 
-	res, err := NewHttpJsonClient[any, models.GetPublicStatusRes](
-		client.ApiEndpoint + path,
-	).Get(nil)
+	res, err := newJsonClient[any, models.GetPublicStatusRes](client, path).GetCtx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -132,11 +271,13 @@ func (client *ApiClient) GetPublicStatus() (*models.GetPublicStatusRes, error) {
 }
 
 func (client *ApiClient) Hello() (*map[string]any, error) {
+	return client.HelloCtx(context.Background())
+}
+
+func (client *ApiClient) HelloCtx(ctx context.Context) (*map[string]any, error) {
 	path := models.HelloPath
 
-	res, err := NewHttpJsonClient[any, map[string]any](
-		client.ApiEndpoint + path,
-	).Get(nil)
+	res, err := newJsonClient[any, map[string]any](client, path).GetCtx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -144,53 +285,63 @@ func (client *ApiClient) Hello() (*map[string]any, error) {
 }
 
 func (client *ApiClient) AuthedHello() (*models.GenericResponse[string], error) {
+	return client.AuthedHelloCtx(context.Background())
+}
+
+func (client *ApiClient) AuthedHelloCtx(ctx context.Context) (*models.GenericResponse[string], error) {
 	path := models.AuthedHelloPath
 
-	jsonClient := NewHttpJsonClient[any, models.GenericResponse[string]](client.ApiEndpoint + path)
-	jsonClient.SetHeaders(client.getHeaders("GET", path, nil))
-	res, err := jsonClient.Get(nil)
+	jsonClient := newJsonClient[any, models.GenericResponse[string]](client, path)
+	jsonClient.WithHeaderFunc(client.signCall)
+	res, err := jsonClient.GetCtx(ctx, nil)
 
 	if err != nil {
-		return nil, fmt.Errorf("error with http request to authed hello: %s", err)
+		return nil, fmt.Errorf("error with http request to authed hello: %w", err)
 	}
 
 	if !res.Success {
-		return res, fmt.Errorf("authed hello was not successful: %s", res.Error)
+		return res, fmt.Errorf("authed hello was not successful: %w", newResponseError("GET", path, res.Error))
 	}
 
 	return res, err
 }
 
 func (client *ApiClient) Markets() (*models.GenericResponse[models.V1GetMarketsResult], error) {
+	return client.MarketsCtx(context.Background())
+}
+
+func (client *ApiClient) MarketsCtx(ctx context.Context) (*models.GenericResponse[models.V1GetMarketsResult], error) {
 	path := models.V1MarketsPath
-	res, err := NewHttpJsonClient[any, models.GenericResponse[models.V1GetMarketsResult]](
-		client.ApiEndpoint + path,
-	).SetHeaders(client.getHeaders("GET", path, nil)).Get(nil)
+	res, err := newJsonClient[any, models.GenericResponse[models.V1GetMarketsResult]](client, path).
+		WithHeaderFunc(client.signCall).GetCtx(ctx, nil)
 
 	if err != nil {
 		return nil, fmt.Errorf("error with http request to v1 markets: %w", err)
 	}
 
 	if !res.Success {
-		return res, fmt.Errorf("error with getting v1 markets: %s", res.Error)
+		return res, fmt.Errorf("error with getting v1 markets: %w", newResponseError("GET", path, res.Error))
 	}
 
 	return res, nil
 }
 
 func (client *ApiClient) GetBalance(req models.GetBalanceReq) (*models.GenericResponse[models.V0GetBalanceRes], error) {
+	return client.GetBalanceCtx(context.Background(), req)
+}
+
+func (client *ApiClient) GetBalanceCtx(ctx context.Context, req models.GetBalanceReq) (*models.GenericResponse[models.V0GetBalanceRes], error) {
 	path := models.V0GetBalancePath
 
-	res, err := NewHttpJsonClient[models.GetBalanceReq, models.GenericResponse[models.V0GetBalanceRes]](
-		client.ApiEndpoint + path,
-	).SetHeaders(client.getHeaders("POST", path, req)).Post(req)
+	res, err := newJsonClient[models.GetBalanceReq, models.GenericResponse[models.V0GetBalanceRes]](client, path).
+		WithHeaderFunc(client.signCall).PostCtx(ctx, req)
 
 	if err != nil {
 		return nil, fmt.Errorf("error with http request to get balance: %w", err)
 	}
 
 	if !res.Success {
-		return nil, fmt.Errorf("error with getting balance %+v: %+v", req, res.Error)
+		return nil, fmt.Errorf("error with getting balance %+v: %w", req, newResponseError("POST", path, res.Error))
 	}
 
 	return res, nil
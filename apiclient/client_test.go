@@ -0,0 +1,136 @@
+package apiclient
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Enclave-Markets/enclave-go/models"
+)
+
+// signingServer answers the status, authed hello and balance endpoints, and rejects requests whose signature does
+// not match the secret of their key id.
+func signingServer(t *testing.T, secrets map[string]string) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == models.StatusPath {
+			_, _ = io.WriteString(w, `{}`)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		secret, ok := secrets[r.Header.Get("ENCLAVE-KEY-ID")]
+		if !ok {
+			http.Error(w, `{"success":false,"error":"invalid api key"}`, http.StatusUnauthorized)
+			return
+		}
+
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(r.Header.Get("ENCLAVE-TIMESTAMP") + r.Method + r.URL.RequestURI() + string(body)))
+		if hex.EncodeToString(mac.Sum(nil)) != r.Header.Get("ENCLAVE-SIGN") {
+			http.Error(w, `{"success":false,"error":"invalid signature"}`, http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case models.AuthedHelloPath:
+			_, _ = io.WriteString(w, `{"success":true,"result":"hello"}`)
+		case models.V0GetBalancePath:
+			_, _ = io.WriteString(w, `{"success":true,"result":{}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+// TestApiClientConcurrentSignedCalls sends signed requests from many goroutines while the key, base headers and
+// clock sync are changed. Run it with -race.
+func TestApiClientConcurrentSignedCalls(t *testing.T) {
+	secrets := map[string]string{"key-a": "secret-a", "key-b": "secret-b"}
+	server := signingServer(t, secrets)
+	defer server.Close()
+
+	client := NewApiClientWithOptions(server.URL, WithCredentials("key-a", "secret-a"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const callers = 8
+	const calls = 25
+
+	var wg sync.WaitGroup
+	errs := make(chan error, callers*calls)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < calls; j++ {
+				var err error
+				if j%2 == 0 {
+					_, err = client.AuthedHelloCtx(ctx)
+				} else {
+					_, err = client.GetBalanceCtx(ctx, models.GetBalanceReq{Symbol: "AVAX"})
+				}
+				if err != nil {
+					errs <- fmt.Errorf("caller %d call %d: %w", i, j, err)
+				}
+			}
+		}(i)
+	}
+
+	var mutators sync.WaitGroup
+	mutators.Add(3)
+	go func() {
+		defer mutators.Done()
+		for i := 0; ctx.Err() == nil; i++ {
+			if i%2 == 0 {
+				client.WithApiKey("key-b", "secret-b")
+			} else {
+				client.WithApiKey("key-a", "secret-a")
+			}
+		}
+	}()
+	go func() {
+		defer mutators.Done()
+		for i := 0; ctx.Err() == nil; i++ {
+			client.SetHeader("X-Caller", fmt.Sprint(i))
+			client.DelHeader("X-Caller")
+		}
+	}()
+	clock, err := client.EnableClockSync(ctx, 0, nil)
+	if err != nil {
+		t.Fatalf("clock sync: %v", err)
+	}
+	go func() {
+		defer mutators.Done()
+		for ctx.Err() == nil {
+			if _, err := clock.Sync(ctx); err != nil && ctx.Err() == nil {
+				errs <- fmt.Errorf("clock sync: %w", err)
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+
+	wg.Wait()
+	cancel()
+	mutators.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
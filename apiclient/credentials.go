@@ -0,0 +1,335 @@
+package apiclient
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Credentials is an Enclave API key.
+type Credentials struct {
+	KeyID     string `json:"keyId"`
+	KeySecret string `json:"keySecret"`
+}
+
+// CredentialProvider loads the API key used by an ApiClient. It is read again on every rotation, see
+// ApiClient.WatchCredentials.
+type CredentialProvider interface {
+	Credentials() (Credentials, error)
+}
+
+// EnvCredentials reads the API key from environment variables.
+type EnvCredentials struct {
+	KeyIDVar     string
+	KeySecretVar string
+}
+
+// NewEnvCredentials reads ENCLAVE_KEY and ENCLAVE_SECRET, the variables used by the example in main.go.
+func NewEnvCredentials() *EnvCredentials {
+	return &EnvCredentials{
+		KeyIDVar:     "ENCLAVE_KEY",
+		KeySecretVar: "ENCLAVE_SECRET",
+	}
+}
+
+func (p *EnvCredentials) Credentials() (Credentials, error) {
+	creds := Credentials{
+		KeyID:     os.Getenv(p.KeyIDVar),
+		KeySecret: os.Getenv(p.KeySecretVar),
+	}
+	if creds.KeyID == "" || creds.KeySecret == "" {
+		return Credentials{}, fmt.Errorf("missing api key in env %s and %s", p.KeyIDVar, p.KeySecretVar)
+	}
+	return creds, nil
+}
+
+// FileCredentials reads the API key from a JSON file holding {"keyId": ..., "keySecret": ...}.
+type FileCredentials struct {
+	Path string
+}
+
+func NewFileCredentials(path string) *FileCredentials {
+	return &FileCredentials{Path: path}
+}
+
+func (p *FileCredentials) Credentials() (Credentials, error) {
+	data, err := os.ReadFile(p.Path)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("failed to read credentials file: %w", err)
+	}
+
+	creds, err := JsonSerializer[Credentials]{}.FromJsonString(string(data))
+	if err != nil {
+		return Credentials{}, fmt.Errorf("failed to parse credentials file %s: %w", p.Path, err)
+	}
+	if creds.KeyID == "" || creds.KeySecret == "" {
+		return Credentials{}, fmt.Errorf("missing api key in credentials file %s", p.Path)
+	}
+	return creds, nil
+}
+
+func (p *FileCredentials) filePath() string {
+	return p.Path
+}
+
+// keystoreFile is the on-disk format of a keystore: the JSON encoded Credentials sealed with AES-256-GCM under a
+// key derived from the passphrase with scrypt.
+type keystoreFile struct {
+	Version    int    `json:"version"`
+	Salt       []byte `json:"salt"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+const (
+	keystoreVersion = 1
+	keystoreScryptN = 1 << 15
+	keystoreScryptR = 8
+	keystoreScryptP = 1
+)
+
+// KeystoreCredentials reads the API key from a passphrase-encrypted keystore file written by WriteKeystore.
+type KeystoreCredentials struct {
+	Path       string
+	Passphrase []byte
+}
+
+func NewKeystoreCredentials(path string, passphrase []byte) *KeystoreCredentials {
+	return &KeystoreCredentials{
+		Path:       path,
+		Passphrase: passphrase,
+	}
+}
+
+func (p *KeystoreCredentials) Credentials() (Credentials, error) {
+	data, err := os.ReadFile(p.Path)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("failed to read keystore: %w", err)
+	}
+
+	ks, err := JsonSerializer[keystoreFile]{}.FromJsonString(string(data))
+	if err != nil {
+		return Credentials{}, fmt.Errorf("failed to parse keystore %s: %w", p.Path, err)
+	}
+	if ks.Version != keystoreVersion {
+		return Credentials{}, fmt.Errorf("unsupported keystore version: %d", ks.Version)
+	}
+
+	aead, err := keystoreCipher(p.Passphrase, ks.Salt, ks.N, ks.R, ks.P)
+	if err != nil {
+		return Credentials{}, err
+	}
+	plaintext, err := aead.Open(nil, ks.Nonce, ks.Ciphertext, nil)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("failed to decrypt keystore %s, wrong passphrase?: %w", p.Path, err)
+	}
+
+	var creds Credentials
+	if err := json.Unmarshal(plaintext, &creds); err != nil {
+		return Credentials{}, fmt.Errorf("failed to parse keystore credentials: %w", err)
+	}
+	return creds, nil
+}
+
+func (p *KeystoreCredentials) filePath() string {
+	return p.Path
+}
+
+// WriteKeystore encrypts creds with passphrase and writes them to path, readable only by the owner.
+func WriteKeystore(path string, creds Credentials, passphrase []byte) error {
+	ks := keystoreFile{
+		Version: keystoreVersion,
+		Salt:    make([]byte, 32),
+		N:       keystoreScryptN,
+		R:       keystoreScryptR,
+		P:       keystoreScryptP,
+	}
+	if _, err := rand.Read(ks.Salt); err != nil {
+		return err
+	}
+
+	aead, err := keystoreCipher(passphrase, ks.Salt, ks.N, ks.R, ks.P)
+	if err != nil {
+		return err
+	}
+	ks.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(ks.Nonce); err != nil {
+		return err
+	}
+
+	plaintext, err := json.Marshal(creds)
+	if err != nil {
+		return err
+	}
+	ks.Ciphertext = aead.Seal(nil, ks.Nonce, plaintext, nil)
+
+	data, err := json.Marshal(ks)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+func keystoreCipher(passphrase []byte, salt []byte, n, r, p int) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, salt, n, r, p, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive keystore key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// CredentialWatchConfig configures when WatchCredentials reloads the API key.
+type CredentialWatchConfig struct {
+	// How often to check the provider. File backed providers are only read again when the file changed, others
+	// are read on every check. Zero disables polling.
+	Interval time.Duration
+
+	// Reload when the process receives SIGHUP.
+	ReloadOnSIGHUP bool
+
+	// Called when a reload rotates the key, the first load included, with the new key id, and when a watched reload
+	// fails, with the key id still in use and the error. Reloads that find the key unchanged are not reported.
+	OnReload func(keyId string, err error)
+}
+
+// CredentialWatcher keeps an ApiClient's API key in sync with a CredentialProvider.
+type CredentialWatcher struct {
+	client   *ApiClient
+	provider CredentialProvider
+	onReload func(string, error)
+
+	mu      sync.Mutex
+	current Credentials
+	modTime time.Time
+	size    int64
+}
+
+// WatchCredentials authenticates the client with the key from provider and rotates it on a live client whenever the
+// provider's key changes, until ctx is done. Requests in flight keep the signature they were sent with; requests
+// signed after a rotation, including retries, use the new key.
+func (client *ApiClient) WatchCredentials(ctx context.Context, provider CredentialProvider, config CredentialWatchConfig) (*CredentialWatcher, error) {
+	w := &CredentialWatcher{
+		client:   client,
+		provider: provider,
+		onReload: config.OnReload,
+	}
+	if err := w.Reload(); err != nil {
+		return nil, err
+	}
+
+	var ticker *time.Ticker
+	var ticks <-chan time.Time
+	if config.Interval > 0 {
+		ticker = time.NewTicker(config.Interval)
+		ticks = ticker.C
+	}
+
+	var hups chan os.Signal
+	if config.ReloadOnSIGHUP {
+		hups = make(chan os.Signal, 1)
+		signal.Notify(hups, syscall.SIGHUP)
+	}
+
+	go func() {
+		if ticker != nil {
+			defer ticker.Stop()
+		}
+		if hups != nil {
+			defer signal.Stop(hups)
+		}
+
+		for {
+			var err error
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticks:
+				err = w.reloadIfChanged()
+			case <-hups:
+				err = w.Reload()
+			}
+			if err != nil && w.onReload != nil {
+				w.onReload(w.KeyID(), err)
+			}
+		}
+	}()
+
+	return w, nil
+}
+
+// Reload reads the provider and swaps the client's key if it changed.
+func (w *CredentialWatcher) Reload() error {
+	return w.reload(false)
+}
+
+// KeyID returns the key id currently in use.
+func (w *CredentialWatcher) KeyID() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.current.KeyID
+}
+
+// reloadIfChanged reloads file backed providers only when their file changed.
+func (w *CredentialWatcher) reloadIfChanged() error {
+	return w.reload(true)
+}
+
+func (w *CredentialWatcher) reload(onlyIfFileChanged bool) error {
+	w.mu.Lock()
+	rotated, err := w.reloadLocked(onlyIfFileChanged)
+	keyId := w.current.KeyID
+	w.mu.Unlock()
+
+	if rotated && w.onReload != nil {
+		w.onReload(keyId, nil)
+	}
+	return err
+}
+
+func (w *CredentialWatcher) reloadLocked(onlyIfFileChanged bool) (bool, error) {
+	var info os.FileInfo
+	if fp, ok := w.provider.(interface{ filePath() string }); ok {
+		var err error
+		info, err = os.Stat(fp.filePath())
+		if err != nil {
+			return false, fmt.Errorf("failed to stat credentials file: %w", err)
+		}
+		if onlyIfFileChanged && info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+			return false, nil
+		}
+	}
+
+	creds, err := w.provider.Credentials()
+	if err != nil {
+		return false, err
+	}
+	// Only a successful load marks the file as seen, a failed one is read again on the next tick
+	if info != nil {
+		w.modTime, w.size = info.ModTime(), info.Size()
+	}
+	if creds == w.current {
+		return false, nil
+	}
+
+	w.client.WithApiKey(creds.KeyID, creds.KeySecret)
+	w.current = creds
+	return true, nil
+}
@@ -0,0 +1,72 @@
+package apiclient
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWatchCredentialsOnReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.json")
+	write := func(data string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{"keyId":"key-a","keySecret":"secret-a"}`)
+
+	type reload struct {
+		keyId string
+		err   error
+	}
+	var mu sync.Mutex
+	var reloads []reload
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := NewApiClient("http://127.0.0.1:1")
+	_, err := client.WatchCredentials(ctx, NewFileCredentials(path), CredentialWatchConfig{
+		Interval: 5 * time.Millisecond,
+		OnReload: func(keyId string, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			reloads = append(reloads, reload{keyId, err})
+		},
+	})
+	if err != nil {
+		t.Fatalf("watch credentials: %v", err)
+	}
+	waitFor := func(n int) []reload {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+			mu.Lock()
+			got := append([]reload(nil), reloads...)
+			mu.Unlock()
+			if len(got) >= n {
+				return got
+			}
+		}
+		t.Fatalf("got %d reloads, want %d", len(reloads), n)
+		return nil
+	}
+
+	// The first load is reported, unchanged checks are not
+	time.Sleep(30 * time.Millisecond)
+	if got := waitFor(1); len(got) != 1 || got[0] != (reload{"key-a", nil}) {
+		t.Fatalf("reloads %+v, want only the first load of key-a", got)
+	}
+
+	// A longer key changes the size of the file, so the change is seen even within the resolution of its mod time
+	write(`{"keyId":"key-bb","keySecret":"secret-bb"}`)
+	if got := waitFor(2); got[1] != (reload{"key-bb", nil}) {
+		t.Fatalf("reload %+v, want the rotation to key-bb", got[1])
+	}
+
+	write(`{"keyId":`)
+	if got := waitFor(3); got[2].keyId != "key-bb" || got[2].err == nil {
+		t.Fatalf("reload %+v, want the error keeping key-bb", got[2])
+	}
+}
@@ -0,0 +1,156 @@
+package apiclient
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Enclave-Markets/enclave-go/models"
+)
+
+// Sentinel errors that an *APIError matches with errors.Is, so callers can branch on the kind of failure without
+// matching on error strings.
+var (
+	ErrUnauthorized           = fmt.Errorf("unauthorized")
+	ErrRateLimited            = fmt.Errorf("rate limited")
+	ErrOrderNotFound          = fmt.Errorf("order not found")
+	ErrInsufficientBalance    = fmt.Errorf("insufficient balance")
+	ErrMarketDisabled         = fmt.Errorf("market disabled")
+	ErrDuplicateClientOrderID = fmt.Errorf("duplicate client order id")
+	ErrOrderFilled            = fmt.Errorf("order already filled")
+)
+
+// APIError is returned when Enclave rejects a request, either with a non 2xx status or with an unsuccessful
+// GenericResponse.
+type APIError struct {
+	// HTTP status of the response. It is http.StatusOK when the failure was only reported in the response body.
+	StatusCode int
+
+	// Error message reported by Enclave, from GenericResponse.Error.
+	Message string
+
+	Method string
+	// Request path without the query string.
+	Path string
+
+	// Request ID returned by the server, if any.
+	RequestID string
+
+	// Raw response body, kept when no error message could be decoded from it.
+	Body string
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = e.Body
+	}
+
+	s := fmt.Sprintf("%s %s: status=%d, error=%s", e.Method, e.Path, e.StatusCode, msg)
+	if e.RequestID != "" {
+		s += ", requestId=" + e.RequestID
+	}
+	return s
+}
+
+// Is reports whether the error belongs to the kind of failure described by one of the sentinel errors.
+func (e *APIError) Is(target error) bool {
+	msg := strings.ToLower(e.Message + " " + e.Body)
+
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden ||
+			strings.Contains(msg, "unauthorized") || strings.Contains(msg, "invalid signature") ||
+			strings.Contains(msg, "invalid api key")
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests || strings.Contains(msg, "rate limit")
+	case ErrOrderNotFound:
+		// A bare 404 only means the order is gone on the path of a single order, elsewhere it may be a missing
+		// route such as /replace or /batch. Error messages decoded from the API are trusted on any order path.
+		_, isOrderPath := orderPathSegment(e.Path)
+		return strings.Contains(msg, "order not found") ||
+			(isOrderPath && strings.Contains(strings.ToLower(e.Message), "not found")) ||
+			(isSingleOrderPath(e.Path) && e.StatusCode == http.StatusNotFound)
+	case ErrInsufficientBalance:
+		return strings.Contains(msg, "insufficient") &&
+			(strings.Contains(msg, "balance") || strings.Contains(msg, "funds") ||
+				strings.Contains(msg, "margin") || strings.Contains(msg, "collateral"))
+	case ErrMarketDisabled:
+		return strings.Contains(msg, "market") &&
+			(strings.Contains(msg, "disabled") || strings.Contains(msg, "halted") || strings.Contains(msg, "not active"))
+	case ErrDuplicateClientOrderID:
+		return strings.Contains(msg, "duplicate") && strings.Contains(msg, "client")
+	case ErrOrderFilled:
+		return strings.Contains(msg, "already filled") || strings.Contains(msg, "fully filled")
+	default:
+		return false
+	}
+}
+
+// orderPathSegment returns what follows the spot or perps orders path in path, and whether path is under one of them.
+func orderPathSegment(path string) (string, bool) {
+	for _, prefix := range []string{models.V1SpotOrdersPath + "/", models.V1PerpsOrdersPath + "/"} {
+		if rest, ok := strings.CutPrefix(path, prefix); ok {
+			return rest, true
+		}
+	}
+	return "", false
+}
+
+// isSingleOrderPath reports whether path addresses one order, e.g. /v1/orders/{id}, and not a sub-resource of it or
+// the batch endpoint.
+func isSingleOrderPath(path string) bool {
+	rest, ok := orderPathSegment(path)
+	return ok && rest != "" && !strings.Contains(rest, "/") && path != models.V1SpotOrdersBatchPath
+}
+
+// newStatusError builds the APIError for a non 2xx response.
+func newStatusError(call *Call, resp *Response) *APIError {
+	body := resp.Body
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Method:     call.Method,
+		Path:       call.URL.Path,
+		RequestID:  resp.Header.Get("X-Request-Id"),
+	}
+
+	var generic models.GenericResponse[json.RawMessage]
+	if err := json.Unmarshal(body, &generic); err == nil && generic.Error != "" {
+		apiErr.Message = generic.Error
+	} else {
+		apiErr.Body = string(body)
+	}
+
+	return apiErr
+}
+
+// newResponseError builds the APIError for a response that was delivered but reported Success false.
+func newResponseError(method string, path string, message string) *APIError {
+	path, _, _ = strings.Cut(path, "?")
+	return &APIError{
+		StatusCode: http.StatusOK,
+		Message:    message,
+		Method:     method,
+		Path:       path,
+	}
+}
+
+// isMissingEndpoint reports whether err is the server answering that it has no such endpoint, for features that fall
+// back to simpler calls on servers without them. A 404 only counts when the router answered it, without an API error
+// message, so a missing order on an existing endpoint is not mistaken for a missing route.
+func isMissingEndpoint(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.StatusCode {
+	case http.StatusMethodNotAllowed:
+		return true
+	case http.StatusNotFound:
+		return apiErr.Message == "" && !errors.Is(apiErr, ErrOrderNotFound)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,190 @@
+package apiclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Enclave-Markets/enclave-go/models"
+)
+
+func (client *ApiClient) GetDepositAddresses(symbol models.Symbol) (*models.GenericResponse[[]models.DepositAddress], error) {
+	return client.GetDepositAddressesCtx(context.Background(), symbol)
+}
+
+// GetDepositAddressesCtx returns the addresses to deposit symbol to, one per supported network.
+func (client *ApiClient) GetDepositAddressesCtx(ctx context.Context, symbol models.Symbol) (*models.GenericResponse[[]models.DepositAddress], error) {
+	path := models.V1DepositAddressesPath + "?symbol=" + string(symbol)
+
+	res, err := newJsonClient[any, models.GenericResponse[[]models.DepositAddress]](client, path).
+		WithHeaderFunc(client.signCall).GetCtx(ctx, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("error in http req get deposit addresses: %w", err)
+	}
+	if !res.Success {
+		return res, fmt.Errorf("bad request get deposit addresses %s: %w", symbol, newResponseError("GET", path, res.Error))
+	}
+
+	return res, nil
+}
+
+func (client *ApiClient) GetDeposits(params models.FundingParams) (*models.V1PageRes[models.Deposit], error) {
+	return client.GetDepositsCtx(context.Background(), params)
+}
+
+func (client *ApiClient) GetDepositsCtx(ctx context.Context, params models.FundingParams) (*models.V1PageRes[models.Deposit], error) {
+	path := models.V1DepositsPath
+	path += params.GetFundingPathParams()
+
+	res, err := newJsonClient[any, models.V1PageRes[models.Deposit]](client, path).
+		WithHeaderFunc(client.signCall).GetCtx(ctx, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("error in http req get deposits: %w", err)
+	}
+
+	return res, err
+}
+
+func (client *ApiClient) Withdraw(req models.WithdrawalReq) (*models.GenericResponse[models.Withdrawal], error) {
+	return client.WithdrawCtx(context.Background(), req)
+}
+
+// WithdrawCtx requests a withdrawal. It is never retried, track it with GetWithdrawal or WaitForWithdrawal.
+func (client *ApiClient) WithdrawCtx(ctx context.Context, req models.WithdrawalReq) (*models.GenericResponse[models.Withdrawal], error) {
+	path := models.V1WithdrawalsPath
+
+	res, err := newJsonClient[models.WithdrawalReq, models.GenericResponse[models.Withdrawal]](client, path).
+		WithHeaderFunc(client.signCall).PostCtx(ctx, req)
+
+	if err != nil {
+		return res, fmt.Errorf("error in http req withdraw: %w", err)
+	}
+	if !res.Success {
+		return res, fmt.Errorf("bad request withdraw %s %s: %w", req.Amount, req.Symbol, newResponseError("POST", path, res.Error))
+	}
+
+	return res, nil
+}
+
+func (client *ApiClient) GetWithdrawals(params models.FundingParams) (*models.V1PageRes[models.Withdrawal], error) {
+	return client.GetWithdrawalsCtx(context.Background(), params)
+}
+
+func (client *ApiClient) GetWithdrawalsCtx(ctx context.Context, params models.FundingParams) (*models.V1PageRes[models.Withdrawal], error) {
+	path := models.V1WithdrawalsPath
+	path += params.GetFundingPathParams()
+
+	res, err := newJsonClient[any, models.V1PageRes[models.Withdrawal]](client, path).
+		WithHeaderFunc(client.signCall).GetCtx(ctx, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("error in http req get withdrawals: %w", err)
+	}
+
+	return res, err
+}
+
+func (client *ApiClient) GetWithdrawal(withdrawalId models.WithdrawalID) (*models.GenericResponse[models.Withdrawal], error) {
+	return client.GetWithdrawalCtx(context.Background(), withdrawalId)
+}
+
+func (client *ApiClient) GetWithdrawalCtx(ctx context.Context, withdrawalId models.WithdrawalID) (*models.GenericResponse[models.Withdrawal], error) {
+	path := models.V1WithdrawalsPath + "/" + string(withdrawalId)
+
+	res, err := newJsonClient[any, models.GenericResponse[models.Withdrawal]](client, path).
+		WithHeaderFunc(client.signCall).GetCtx(ctx, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("error in http req get withdrawal: %w", err)
+	}
+	if !res.Success {
+		return res, fmt.Errorf("bad request get withdrawal %s: %w", withdrawalId, newResponseError("GET", path, res.Error))
+	}
+
+	return res, nil
+}
+
+// WaitForWithdrawal polls the withdrawal every interval until it reaches a final state, and returns it. Failed
+// polls are retried until ctx is done.
+func (client *ApiClient) WaitForWithdrawal(ctx context.Context, withdrawalId models.WithdrawalID, interval time.Duration) (*models.Withdrawal, error) {
+	var lastErr error
+	for {
+		res, err := client.GetWithdrawalCtx(ctx, withdrawalId)
+		if err == nil && res.Result.State.IsFinal() {
+			return &res.Result, nil
+		}
+		lastErr = err
+
+		select {
+		case <-ctx.Done():
+			if lastErr != nil {
+				return nil, fmt.Errorf("%w, last error: %w", ctx.Err(), lastErr)
+			}
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (client *ApiClient) CancelWithdrawal(withdrawalId models.WithdrawalID) (*models.GenericResponse[any], error) {
+	return client.CancelWithdrawalCtx(context.Background(), withdrawalId)
+}
+
+// CancelWithdrawalCtx cancels a withdrawal that is still pending.
+func (client *ApiClient) CancelWithdrawalCtx(ctx context.Context, withdrawalId models.WithdrawalID) (*models.GenericResponse[any], error) {
+	path := models.V1WithdrawalsPath + "/" + string(withdrawalId)
+
+	res, err := newJsonClient[any, models.GenericResponse[any]](client, path).
+		WithHeaderFunc(client.signCall).DeleteCtx(ctx, nil)
+
+	if err != nil {
+		return res, fmt.Errorf("error in http req cancel withdrawal: %w", err)
+	}
+	if !res.Success {
+		return res, fmt.Errorf("bad request cancel withdrawal %s: %w", withdrawalId, newResponseError("DELETE", path, res.Error))
+	}
+
+	return res, nil
+}
+
+func (client *ApiClient) Transfer(req models.TransferReq) (*models.GenericResponse[models.Transfer], error) {
+	return client.TransferCtx(context.Background(), req)
+}
+
+// TransferCtx moves funds between the spot and margin accounts.
+func (client *ApiClient) TransferCtx(ctx context.Context, req models.TransferReq) (*models.GenericResponse[models.Transfer], error) {
+	path := models.V1TransfersPath
+
+	res, err := newJsonClient[models.TransferReq, models.GenericResponse[models.Transfer]](client, path).
+		WithHeaderFunc(client.signCall).PostCtx(ctx, req)
+
+	if err != nil {
+		return res, fmt.Errorf("error in http req transfer: %w", err)
+	}
+	if !res.Success {
+		return res, fmt.Errorf("bad request transfer %s %s from %s to %s: %w", req.Amount, req.Symbol, req.From, req.To,
+			newResponseError("POST", path, res.Error))
+	}
+
+	return res, nil
+}
+
+func (client *ApiClient) GetTransfers(params models.FundingParams) (*models.V1PageRes[models.Transfer], error) {
+	return client.GetTransfersCtx(context.Background(), params)
+}
+
+func (client *ApiClient) GetTransfersCtx(ctx context.Context, params models.FundingParams) (*models.V1PageRes[models.Transfer], error) {
+	path := models.V1TransfersPath
+	path += params.GetFundingPathParams()
+
+	res, err := newJsonClient[any, models.V1PageRes[models.Transfer]](client, path).
+		WithHeaderFunc(client.signCall).GetCtx(ctx, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("error in http req get transfers: %w", err)
+	}
+
+	return res, err
+}
@@ -8,6 +8,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"time"
 
 	"golang.org/x/time/rate"
 )
@@ -15,6 +17,12 @@ import (
 type HttpJsonClient[REQUEST_T any, REPLY_T any] struct {
 	ApiEndpoint   string
 	headers       map[string]string
+	headerFunc    func(ctx context.Context, call *Call) (map[string]string, error)
+	httpClient    *http.Client
+	retryPolicy   *RetryPolicy
+	rateLimiter   *RateLimiter
+	rateLimitCost int
+	interceptors  []Interceptor
 	IsCSVResponse bool
 }
 
@@ -30,6 +38,46 @@ func (cl *HttpJsonClient[REQUEST_T, REPLY_T]) WithHeader(key string, value strin
 	return cl
 }
 
+// WithHttpClient sets the http.Client used to send requests. A nil client falls back to http.DefaultClient.
+func (cl *HttpJsonClient[REQUEST_T, REPLY_T]) WithHttpClient(httpClient *http.Client) *HttpJsonClient[REQUEST_T, REPLY_T] {
+	cl.httpClient = httpClient
+	return cl
+}
+
+// WithHeaderFunc sets a function called right before every attempt is sent, after the interceptors and any rate
+// limit wait, to compute extra headers of the call, e.g. a signature that must cover the final path and body and
+// carry a fresh timestamp. It gets the context of the call.
+func (cl *HttpJsonClient[REQUEST_T, REPLY_T]) WithHeaderFunc(headerFunc func(ctx context.Context, call *Call) (map[string]string, error)) *HttpJsonClient[REQUEST_T, REPLY_T] {
+	cl.headerFunc = headerFunc
+	return cl
+}
+
+// WithRetryPolicy retries idempotent requests that fail transiently. A nil policy disables retries.
+func (cl *HttpJsonClient[REQUEST_T, REPLY_T]) WithRetryPolicy(retryPolicy *RetryPolicy) *HttpJsonClient[REQUEST_T, REPLY_T] {
+	cl.retryPolicy = retryPolicy
+	return cl
+}
+
+// WithRateLimiter makes every attempt wait on the limiter bucket of the request and pauses that bucket on 429
+// responses. A nil limiter disables client-side rate limiting.
+func (cl *HttpJsonClient[REQUEST_T, REPLY_T]) WithRateLimiter(rateLimiter *RateLimiter) *HttpJsonClient[REQUEST_T, REPLY_T] {
+	cl.rateLimiter = rateLimiter
+	return cl
+}
+
+// WithRateLimitCost makes every attempt take n tokens of its rate limit bucket instead of one, for requests the
+// exchange counts as n requests such as batches of n orders.
+func (cl *HttpJsonClient[REQUEST_T, REPLY_T]) WithRateLimitCost(n int) *HttpJsonClient[REQUEST_T, REPLY_T] {
+	cl.rateLimitCost = n
+	return cl
+}
+
+// WithInterceptors appends interceptors that run around every attempt of the request, see Interceptor.
+func (cl *HttpJsonClient[REQUEST_T, REPLY_T]) WithInterceptors(interceptors ...Interceptor) *HttpJsonClient[REQUEST_T, REPLY_T] {
+	cl.interceptors = append(cl.interceptors, interceptors...)
+	return cl
+}
+
 func (cl *HttpJsonClient[REQUEST_T, REPLY_T]) SetHeaders(headers map[string]string) *HttpJsonClient[REQUEST_T, REPLY_T] {
 	for k, v := range headers {
 		cl.headers[k] = v
@@ -38,20 +86,38 @@ func (cl *HttpJsonClient[REQUEST_T, REPLY_T]) SetHeaders(headers map[string]stri
 }
 
 func (cl *HttpJsonClient[REQUEST_T, REPLY_T]) Post(request REQUEST_T) (*REPLY_T, error) {
-	return cl.Do("POST", request)
+	return cl.PostCtx(context.Background(), request)
+}
+
+func (cl *HttpJsonClient[REQUEST_T, REPLY_T]) PostCtx(ctx context.Context, request REQUEST_T) (*REPLY_T, error) {
+	return cl.DoCtx(ctx, "POST", request)
 }
 
 func (cl *HttpJsonClient[REQUEST_T, REPLY_T]) Get(request REQUEST_T) (*REPLY_T, error) {
-	return cl.Do("GET", request)
+	return cl.GetCtx(context.Background(), request)
+}
+
+func (cl *HttpJsonClient[REQUEST_T, REPLY_T]) GetCtx(ctx context.Context, request REQUEST_T) (*REPLY_T, error) {
+	return cl.DoCtx(ctx, "GET", request)
 }
 
 func (cl *HttpJsonClient[REQUEST_T, REPLY_T]) Delete(request REQUEST_T) (*REPLY_T, error) {
-	return cl.Do("DELETE", request)
+	return cl.DeleteCtx(context.Background(), request)
+}
+
+func (cl *HttpJsonClient[REQUEST_T, REPLY_T]) DeleteCtx(ctx context.Context, request REQUEST_T) (*REPLY_T, error) {
+	return cl.DoCtx(ctx, "DELETE", request)
 }
 
 var ErrEmptyResponseBody = fmt.Errorf("response body is empty")
 
 func (cl *HttpJsonClient[REQUEST_T, REPLY_T]) Do(method string, request REQUEST_T) (*REPLY_T, error) {
+	return cl.DoCtx(context.Background(), method, request)
+}
+
+// DoCtx sends the request and decodes the reply. The context is attached to the underlying http.Request so
+// cancellation and deadlines abort the call at the transport.
+func (cl *HttpJsonClient[REQUEST_T, REPLY_T]) DoCtx(ctx context.Context, method string, request REQUEST_T) (*REPLY_T, error) {
 	jsonStr, err := JsonSerializer[REQUEST_T]{}.ToJsonString(request)
 	if err != nil {
 		return nil, err
@@ -64,41 +130,60 @@ func (cl *HttpJsonClient[REQUEST_T, REPLY_T]) Do(method string, request REQUEST_
 	// }
 	// fmt.Printf("%s %s\n%s\n", method, cl.ApiEndpoint, prettyJsonStr)
 
-	var reqBody io.Reader
-	// Allow the HttpJsonClient to be used for endpoints that don't expect a request body
-	// by not sending one if the json representation of the request parameter is "null"
-	if jsonStr != "null" {
-		reqBody = bytes.NewBuffer([]byte(jsonStr))
+	// Only idempotent requests are safe to send again without knowing whether the first attempt landed.
+	retryPolicy := cl.retryPolicy
+	if !isIdempotent(method) {
+		retryPolicy = nil
 	}
 
-	req, err := http.NewRequest(method, cl.ApiEndpoint, reqBody)
+	for attempt := 0; ; attempt++ {
+		reply, err := cl.doOnce(ctx, method, jsonStr)
+		if !retryPolicy.canRetry(attempt) || !retryPolicy.isRetryable(err) {
+			return reply, err
+		}
+
+		if waitErr := retryPolicy.wait(ctx, attempt); waitErr != nil {
+			return reply, err
+		}
+	}
+}
+
+// doOnce sends a single attempt of the request through the interceptors.
+func (cl *HttpJsonClient[REQUEST_T, REPLY_T]) doOnce(ctx context.Context, method string, jsonStr string) (*REPLY_T, error) {
+	reqUrl, err := url.Parse(cl.ApiEndpoint)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Content-Type", "application/json")
 
-	for k, v := range cl.headers {
-		req.Header.Set(k, v)
+	call := &Call{
+		Method: method,
+		URL:    reqUrl,
+		Header: http.Header{},
+	}
+	// Allow the HttpJsonClient to be used for endpoints that don't expect a request body
+	// by not sending one if the json representation of the request parameter is "null"
+	if jsonStr != "null" {
+		call.Body = []byte(jsonStr)
 	}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
+	call.Header.Set("Content-Type", "application/json")
+	for k, v := range cl.headers {
+		call.Header.Set(k, v)
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	resp, err := chainInterceptors(cl.interceptors, cl.send)(ctx, call)
 	if err != nil {
 		return nil, err
 	}
+	body := resp.Body
 
 	if !(resp.StatusCode == 200 || resp.StatusCode == 201 || resp.StatusCode == 202) {
 		reply, err := JsonSerializer[REPLY_T]{}.FromJsonString(string(body))
-		err_text := fmt.Errorf("response: status=%d, body=%s", resp.StatusCode, body)
+		apiErr := newStatusError(call, resp)
 		if err != nil {
-			return nil, err_text
+			return nil, apiErr
 		}
-		return &reply, err_text
+		return &reply, apiErr
 	}
 
 	if cl.IsCSVResponse {
@@ -119,6 +204,66 @@ func (cl *HttpJsonClient[REQUEST_T, REPLY_T]) Do(method string, request REQUEST_
 	}
 }
 
+// send is the innermost Invoker: it waits on the rate limiter, computes the headers of the header func and performs
+// the http round trip. Headers are computed last so they cover the call as the interceptors left it, and a call queued
+// behind a paused bucket is not sent with a stale timestamp.
+func (cl *HttpJsonClient[REQUEST_T, REPLY_T]) send(ctx context.Context, call *Call) (*Response, error) {
+	if cl.rateLimiter != nil {
+		if err := cl.rateLimiter.WaitN(ctx, call.Method, call.URL.Path, max(cl.rateLimitCost, 1)); err != nil {
+			return nil, err
+		}
+	}
+
+	var reqBody io.Reader
+	if call.Body != nil {
+		reqBody = bytes.NewReader(call.Body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, call.Method, call.URL.String(), reqBody)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = call.Header.Clone()
+	if cl.headerFunc != nil {
+		headers, err := cl.headerFunc(ctx, call)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+	}
+
+	httpClient := cl.httpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	start := time.Now()
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if cl.rateLimiter != nil {
+		cl.rateLimiter.observe(call.Method, call.URL.Path, resp.StatusCode, resp.Header)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{
+		StatusCode:    resp.StatusCode,
+		Header:        resp.Header,
+		Body:          body,
+		RequestHeader: req.Header,
+		Latency:       time.Since(start),
+	}, nil
+}
+
 type RateLimitedSecureHttpJsonClient[REQUEST_T any, REPLY_T any] struct {
 	client      *HttpJsonClient[REQUEST_T, REPLY_T]
 	rateLimiter *rate.Limiter
@@ -143,7 +288,7 @@ func (rlc *RateLimitedSecureHttpJsonClient[REQUEST_T, REPLY_T]) Post(request REQ
 		return nil, err
 	}
 
-	return rlc.client.Post(request)
+	return rlc.client.PostCtx(ctx, request)
 }
 
 func (rlc *RateLimitedSecureHttpJsonClient[REQUEST_T, REPLY_T]) Get(request REQUEST_T, ctx context.Context) (*REPLY_T, error) {
@@ -152,7 +297,16 @@ func (rlc *RateLimitedSecureHttpJsonClient[REQUEST_T, REPLY_T]) Get(request REQU
 		return nil, err
 	}
 
-	return rlc.client.Do("GET", request)
+	return rlc.client.DoCtx(ctx, "GET", request)
+}
+
+func (rlc *RateLimitedSecureHttpJsonClient[REQUEST_T, REPLY_T]) Delete(request REQUEST_T, ctx context.Context) (*REPLY_T, error) {
+	err := rlc.rateLimiter.Wait(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return rlc.client.DeleteCtx(ctx, request)
 }
 
 type JsonSerializer[T any] struct {
@@ -0,0 +1,65 @@
+package apiclient
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Call is one attempt of a request to Enclave as seen by interceptors. Auth headers are not part of it, they are
+// computed after the interceptors and the rate limiter, right before the call is sent, so an interceptor may change
+// the method, path, query or body and the signature covers the change. The headers sent, auth headers included, are
+// in Response.RequestHeader.
+type Call struct {
+	Method string
+	URL    *url.URL
+	Header http.Header
+
+	// JSON request body, nil when the request has none.
+	Body []byte
+}
+
+// Response is the outcome of a Call.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+
+	// Headers the call was sent with, including the ENCLAVE-KEY-ID, ENCLAVE-TIMESTAMP and ENCLAVE-SIGN auth headers.
+	// Nil for short-circuited responses.
+	RequestHeader http.Header
+
+	// Time spent in the http round trip, including reading the body. Zero for short-circuited responses.
+	Latency time.Duration
+}
+
+// Invoker sends a Call and returns its Response.
+type Invoker func(ctx context.Context, call *Call) (*Response, error)
+
+// Interceptor wraps every call made by an ApiClient. It may inspect or mutate the call, then either invoke next to
+// continue down the chain or return its own response or error to short-circuit it.
+//
+// Interceptors run in the order they were registered: the first one sees the call first and the response last.
+// They run once per attempt, inside the retry loop and outside the rate limiter, so a short-circuited call never
+// consumes a rate limit token.
+type Interceptor func(ctx context.Context, call *Call, next Invoker) (*Response, error)
+
+// WithInterceptors registers interceptors run around every call of the client, in the given order.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(cfg *clientConfig) {
+		cfg.interceptors = append(cfg.interceptors, interceptors...)
+	}
+}
+
+// chainInterceptors returns an Invoker running interceptors in order around last.
+func chainInterceptors(interceptors []Interceptor, last Invoker) Invoker {
+	next := last
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, inner := interceptors[i], next
+		next = func(ctx context.Context, call *Call) (*Response, error) {
+			return interceptor(ctx, call, inner)
+		}
+	}
+	return next
+}
@@ -0,0 +1,77 @@
+package apiclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/Enclave-Markets/enclave-go/models"
+)
+
+// TestInterceptorsSeeSignedHeaders checks that a call changed by an interceptor is signed as sent, and that the
+// interceptors see the auth headers it was sent with.
+func TestInterceptorsSeeSignedHeaders(t *testing.T) {
+	server := signingServer(t, map[string]string{"key": "secret"})
+	defer server.Close()
+
+	var order []string
+	var sent http.Header
+	client := NewApiClientWithOptions(server.URL,
+		WithCredentials("key", "secret"),
+		WithInterceptors(
+			func(ctx context.Context, call *Call, next Invoker) (*Response, error) {
+				order = append(order, "outer")
+				resp, err := next(ctx, call)
+				if resp != nil {
+					sent = resp.RequestHeader
+				}
+				return resp, err
+			},
+			func(ctx context.Context, call *Call, next Invoker) (*Response, error) {
+				order = append(order, "inner")
+				// The signature must cover the changed query
+				query := call.URL.Query()
+				query.Set("symbol", "USDC")
+				call.URL.RawQuery = query.Encode()
+				return next(ctx, call)
+			},
+		),
+	)
+
+	if _, err := client.GetBalance(models.GetBalanceReq{Symbol: "AVAX"}); err != nil {
+		t.Fatalf("get balance: %v", err)
+	}
+
+	if len(order) != 2 || order[0] != "outer" || order[1] != "inner" {
+		t.Fatalf("interceptors ran in order %v", order)
+	}
+	for _, header := range []string{"ENCLAVE-KEY-ID", "ENCLAVE-TIMESTAMP", "ENCLAVE-SIGN"} {
+		if sent.Get(header) == "" {
+			t.Fatalf("request header %s not seen by the interceptor, got %v", header, sent)
+		}
+	}
+}
+
+func TestInterceptorShortCircuit(t *testing.T) {
+	server := signingServer(t, map[string]string{"key": "secret"})
+	defer server.Close()
+
+	blocked := errors.New("blocked")
+	client := NewApiClientWithOptions(server.URL,
+		WithCredentials("key", "secret"),
+		WithRateLimits(RateLimitConfig{Other: RateLimit{PerSecond: 1, Burst: 1}}),
+		WithInterceptors(func(ctx context.Context, call *Call, next Invoker) (*Response, error) {
+			return nil, blocked
+		}),
+	)
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.AuthedHello(); !errors.Is(err, blocked) {
+			t.Fatalf("call %d: got %v, want the interceptor error", i, err)
+		}
+	}
+	if tokens := client.RateLimiter().State(BucketOther).Tokens; tokens < 1 {
+		t.Fatalf("short-circuited calls took rate limit tokens, %v left", tokens)
+	}
+}
@@ -0,0 +1,49 @@
+package apiclient
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Enclave-Markets/enclave-go/models"
+)
+
+func (client *ApiClient) GetMarginAccount() (*models.GenericResponse[models.MarginAccount], error) {
+	return client.GetMarginAccountCtx(context.Background())
+}
+
+// GetMarginAccountCtx returns the margin account with its collateral, positions, margins and health ratio.
+func (client *ApiClient) GetMarginAccountCtx(ctx context.Context) (*models.GenericResponse[models.MarginAccount], error) {
+	path := models.V1MarginAccountPath
+
+	res, err := newJsonClient[any, models.GenericResponse[models.MarginAccount]](client, path).
+		WithHeaderFunc(client.signCall).GetCtx(ctx, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("error in http req get margin account: %w", err)
+	}
+	if !res.Success {
+		return res, fmt.Errorf("bad request get margin account: %w", newResponseError("GET", path, res.Error))
+	}
+
+	return res, nil
+}
+
+func (client *ApiClient) GetCollateral() (*models.GenericResponse[[]models.Collateral], error) {
+	return client.GetCollateralCtx(context.Background())
+}
+
+func (client *ApiClient) GetCollateralCtx(ctx context.Context) (*models.GenericResponse[[]models.Collateral], error) {
+	path := models.V1MarginCollateralPath
+
+	res, err := newJsonClient[any, models.GenericResponse[[]models.Collateral]](client, path).
+		WithHeaderFunc(client.signCall).GetCtx(ctx, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("error in http req get collateral: %w", err)
+	}
+	if !res.Success {
+		return res, fmt.Errorf("bad request get collateral: %w", newResponseError("GET", path, res.Error))
+	}
+
+	return res, nil
+}
@@ -0,0 +1,161 @@
+package apiclient
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Enclave-Markets/enclave-go/models"
+)
+
+// MarketStatusEvent is a market's status transition, e.g. active to halted.
+type MarketStatusEvent struct {
+	Market models.Market
+
+	// Previous is empty for a market that was just listed, Status is empty for a market that was removed.
+	Previous models.MarketStatus
+	Status   models.MarketStatus
+
+	Time time.Time
+}
+
+type MarketStatusWatchConfig struct {
+	// How often the status endpoint is polled. Defaults to 5 seconds.
+	Interval time.Duration
+
+	// Reject orders client-side, with ErrMarketDisabled, when the last known status of their market does not accept
+	// them. Gating stops when the watch context is done.
+	GateOrders bool
+
+	// Called from the watcher goroutine for every transition.
+	OnChange func(MarketStatusEvent)
+
+	// Called from the watcher goroutine when a poll fails. The last known statuses are kept.
+	OnError func(error)
+}
+
+// MarketStatusWatcher tracks the status of every market.
+type MarketStatusWatcher struct {
+	client *ApiClient
+	config MarketStatusWatchConfig
+
+	mu       sync.RWMutex
+	statuses map[models.Market]models.MarketStatus
+	loaded   bool
+}
+
+// WatchMarketStatus loads the market statuses and polls them until ctx is done, reporting every transition.
+func (client *ApiClient) WatchMarketStatus(ctx context.Context, config MarketStatusWatchConfig) (*MarketStatusWatcher, error) {
+	if config.Interval <= 0 {
+		config.Interval = 5 * time.Second
+	}
+
+	w := &MarketStatusWatcher{
+		client:   client,
+		config:   config,
+		statuses: map[models.Market]models.MarketStatus{},
+	}
+	if err := w.Poll(ctx); err != nil {
+		return nil, err
+	}
+
+	if config.GateOrders {
+		client.mu.Lock()
+		client.marketGate = w
+		client.mu.Unlock()
+	}
+
+	go func() {
+		ticker := time.NewTicker(config.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				client.mu.Lock()
+				if client.marketGate == w {
+					client.marketGate = nil
+				}
+				client.mu.Unlock()
+				return
+			case <-ticker.C:
+			}
+
+			if err := w.Poll(ctx); err != nil && ctx.Err() == nil && config.OnError != nil {
+				config.OnError(err)
+			}
+		}
+	}()
+
+	return w, nil
+}
+
+// Poll fetches the statuses now and reports the transitions since the last poll. The first poll only loads them.
+func (w *MarketStatusWatcher) Poll(ctx context.Context) error {
+	res, err := w.client.GetPublicStatusCtx(ctx)
+	if err != nil {
+		return fmt.Errorf("error polling market status: %w", err)
+	}
+
+	now := time.Now()
+	var events []MarketStatusEvent
+
+	w.mu.Lock()
+	for market, status := range res.MarketStatuses {
+		previous, ok := w.statuses[market]
+		if w.loaded && (!ok || previous != models.MarketStatus(status)) {
+			events = append(events, MarketStatusEvent{Market: market, Previous: previous, Status: models.MarketStatus(status), Time: now})
+		}
+		w.statuses[market] = models.MarketStatus(status)
+	}
+	for market, previous := range w.statuses {
+		if _, ok := res.MarketStatuses[market]; !ok {
+			events = append(events, MarketStatusEvent{Market: market, Previous: previous, Time: now})
+			delete(w.statuses, market)
+		}
+	}
+	w.loaded = true
+	w.mu.Unlock()
+
+	if w.config.OnChange != nil {
+		for _, event := range events {
+			w.config.OnChange(event)
+		}
+	}
+	return nil
+}
+
+// Status returns the last known status of market.
+func (w *MarketStatusWatcher) Status(market models.Market) (models.MarketStatus, bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	status, ok := w.statuses[market]
+	return status, ok
+}
+
+// Statuses returns the last known status of every market.
+func (w *MarketStatusWatcher) Statuses() map[models.Market]models.MarketStatus {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	statuses := make(map[models.Market]models.MarketStatus, len(w.statuses))
+	for market, status := range w.statuses {
+		statuses[market] = status
+	}
+	return statuses
+}
+
+// CheckOrder returns an ErrMarketDisabled error if the last known status of the order's market does not accept it.
+// Markets with no known status are let through.
+func (w *MarketStatusWatcher) CheckOrder(req models.AddOrderReq) error {
+	status, ok := w.Status(req.Market)
+	if !ok || status.AcceptsOrder(req.PostOnly) {
+		return nil
+	}
+	if status == models.MarketStatusPostOnly {
+		return fmt.Errorf("order rejected client-side: %w: %s is %s, only post-only orders are accepted", ErrMarketDisabled, req.Market, status)
+	}
+	return fmt.Errorf("order rejected client-side: %w: %s is %s", ErrMarketDisabled, req.Market, status)
+}
@@ -0,0 +1,128 @@
+package apiclient
+
+import (
+	"net/http"
+	"time"
+)
+
+// Option configures an ApiClient built with NewApiClientWithOptions.
+type Option func(*clientConfig)
+
+type clientConfig struct {
+	httpClient   *http.Client
+	transport    http.RoundTripper
+	timeout      time.Duration
+	retryPolicy  *RetryPolicy
+	rateLimits   *RateLimitConfig
+	interceptors []Interceptor
+	userAgent    string
+	headers      map[string]string
+	signer       Signer
+
+	batchConcurrency int
+}
+
+// WithHttpClient sends every request through httpClient. The client is copied, so later options such as
+// WithTimeout or WithTransport do not mutate the caller's value.
+func WithHttpClient(httpClient *http.Client) Option {
+	return func(cfg *clientConfig) {
+		cfg.httpClient = httpClient
+	}
+}
+
+// WithTransport sets the http.RoundTripper used to send requests, e.g. for proxies, custom TLS roots, connection
+// pool sizing or a test transport.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(cfg *clientConfig) {
+		cfg.transport = transport
+	}
+}
+
+// WithTimeout sets the default timeout applied to each request. A context deadline still applies on top of it.
+func WithTimeout(timeout time.Duration) Option {
+	return func(cfg *clientConfig) {
+		cfg.timeout = timeout
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(userAgent string) Option {
+	return func(cfg *clientConfig) {
+		cfg.userAgent = userAgent
+	}
+}
+
+// WithHeaders adds base headers sent with every request.
+func WithHeaders(headers map[string]string) Option {
+	return func(cfg *clientConfig) {
+		for k, v := range headers {
+			cfg.headers[k] = v
+		}
+	}
+}
+
+// WithCredentials authenticates the client with an API key, see ApiClient.WithApiKey.
+func WithCredentials(keyId, keySecret string) Option {
+	return func(cfg *clientConfig) {
+		cfg.signer = NewHmacSigner(keyId, keySecret)
+	}
+}
+
+// WithKeySigner authenticates the client with signatures computed by signer, see ApiClient.WithSigner.
+func WithKeySigner(signer Signer) Option {
+	return func(cfg *clientConfig) {
+		cfg.signer = signer
+	}
+}
+
+// NewApiClientWithOptions returns a client for apiEndpoint configured by opts. Without options it behaves like
+// NewApiClient.
+func NewApiClientWithOptions(apiEndpoint string, opts ...Option) *ApiClient {
+	cfg := &clientConfig{headers: map[string]string{}}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	client := NewApiClient(apiEndpoint)
+	for k, v := range cfg.headers {
+		client.Headers[k] = v
+	}
+	if cfg.userAgent != "" {
+		client.Headers["User-Agent"] = cfg.userAgent
+	}
+	client.retryPolicy = cfg.retryPolicy
+	client.interceptors = cfg.interceptors
+	client.batchConcurrency = cfg.batchConcurrency
+	if cfg.rateLimits != nil {
+		client.rateLimiter = NewRateLimiter(*cfg.rateLimits)
+	}
+	if cfg.signer != nil {
+		client.WithSigner(cfg.signer)
+	}
+
+	if cfg.httpClient != nil || cfg.transport != nil || cfg.timeout != 0 {
+		httpClient := &http.Client{}
+		if cfg.httpClient != nil {
+			*httpClient = *cfg.httpClient
+		}
+		if cfg.transport != nil {
+			httpClient.Transport = cfg.transport
+		}
+		if cfg.timeout != 0 {
+			httpClient.Timeout = cfg.timeout
+		}
+		client.httpClient = httpClient
+	}
+
+	return client
+}
+
+// NewApiClientFromEnvWithOptions is NewApiClientWithOptions for the "sandbox" or "prod" environment.
+func NewApiClientFromEnvWithOptions(env string, opts ...Option) (*ApiClient, error) {
+	apiUrl, err := envEndpoint(env)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewApiClientWithOptions(apiUrl, opts...), nil
+}
@@ -0,0 +1,306 @@
+package apiclient
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Enclave-Markets/enclave-go/models"
+	"github.com/shopspring/decimal"
+)
+
+func (client *ApiClient) AddPerpsOrder(req models.AddOrderReq) (*models.GenericResponse[models.ApiOrder], error) {
+	return client.AddPerpsOrderCtx(context.Background(), req)
+}
+
+// AddPerpsOrderCtx places a perps order. Set ReduceOnly to only ever shrink the position. Orders gated by a
+// MarketStatusWatcher are rejected before being sent.
+func (client *ApiClient) AddPerpsOrderCtx(ctx context.Context, req models.AddOrderReq) (*models.GenericResponse[models.ApiOrder], error) {
+	client.mu.RLock()
+	gate := client.marketGate
+	client.mu.RUnlock()
+	if gate != nil {
+		if err := gate.CheckOrder(req); err != nil {
+			return nil, err
+		}
+	}
+
+	path := models.V1PerpsOrdersPath
+
+	res, err := newJsonClient[models.AddOrderReq, models.GenericResponse[models.ApiOrder]](client, path).
+		WithHeaderFunc(client.signCall).PostCtx(ctx, req)
+
+	if err != nil {
+		return res, fmt.Errorf("error with http req in perps add order: %w", err)
+	}
+	if !res.Success {
+		return res, fmt.Errorf("error in perps add order %v: %w", req, newResponseError("POST", path, res.Error))
+	}
+
+	return res, err
+}
+
+func (client *ApiClient) GetPerpsOrders() (*models.GenericResponse[[]models.ApiOrder], error) {
+	return client.GetPerpsOrdersCtx(context.Background())
+}
+
+func (client *ApiClient) GetPerpsOrdersCtx(ctx context.Context) (*models.GenericResponse[[]models.ApiOrder], error) {
+	path := models.V1PerpsOrdersPath
+
+	res, err := newJsonClient[any, models.GenericResponse[[]models.ApiOrder]](client, path).
+		WithHeaderFunc(client.signCall).GetCtx(ctx, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("error in http req perps get orders: %w", err)
+	}
+	if !res.Success {
+		return res, fmt.Errorf("bad request perps get orders: %w", newResponseError("GET", path, res.Error))
+	}
+
+	return res, nil
+}
+
+func (client *ApiClient) GetPerpsOrdersByMarket(market string) (*models.GenericResponse[[]models.ApiOrder], error) {
+	return client.GetPerpsOrdersByMarketCtx(context.Background(), market)
+}
+
+func (client *ApiClient) GetPerpsOrdersByMarketCtx(ctx context.Context, market string) (*models.GenericResponse[[]models.ApiOrder], error) {
+	path := models.V1PerpsOrdersPath + "?market=" + market
+
+	res, err := newJsonClient[any, models.GenericResponse[[]models.ApiOrder]](client, path).
+		WithHeaderFunc(client.signCall).GetCtx(ctx, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("error in http req perps get orders: %w", err)
+	}
+	if !res.Success {
+		return res, fmt.Errorf("bad request perps get orders: %w", newResponseError("GET", path, res.Error))
+	}
+
+	return res, nil
+}
+
+func (client *ApiClient) GetPerpsOrder(order models.OrderRef) (*models.GenericResponse[models.ApiOrder], error) {
+	return client.GetPerpsOrderCtx(context.Background(), order)
+}
+
+// GetPerpsOrderCtx returns a perps order addressed by its exchange OrderID or by its ClientOrderID.
+func (client *ApiClient) GetPerpsOrderCtx(ctx context.Context, order models.OrderRef) (*models.GenericResponse[models.ApiOrder], error) {
+	path := models.V1PerpsOrdersPath + "/" + order.PathSegment()
+
+	res, err := newJsonClient[any, models.GenericResponse[models.ApiOrder]](client, path).
+		WithHeaderFunc(client.signCall).GetCtx(ctx, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("error in http req perps get order: %w", err)
+	}
+	if !res.Success {
+		return res, fmt.Errorf("bad request perps get order %s: %w", order.PathSegment(), newResponseError("GET", path, res.Error))
+	}
+
+	return res, nil
+}
+
+func (client *ApiClient) CancelAllPerpsOrders() error {
+	return client.CancelAllPerpsOrdersCtx(context.Background())
+}
+
+func (client *ApiClient) CancelAllPerpsOrdersCtx(ctx context.Context) error {
+	path := models.V1PerpsOrdersPath
+
+	res, err := newJsonClient[any, models.GenericResponse[any]](client, path).
+		WithHeaderFunc(client.signCall).DeleteCtx(ctx, nil)
+
+	if err != nil {
+		return fmt.Errorf("error in http req perps delete all orders: %w", err)
+	}
+	if !res.Success {
+		return fmt.Errorf("bad request perps delete all orders: %w", newResponseError("DELETE", path, res.Error))
+	}
+
+	return nil
+}
+
+func (client *ApiClient) CancelPerpsOrder(order models.OrderRef) (*models.GenericResponse[any], error) {
+	return client.CancelPerpsOrderCtx(context.Background(), order)
+}
+
+// CancelPerpsOrderCtx cancels a perps order addressed by its exchange OrderID or by its ClientOrderID.
+func (client *ApiClient) CancelPerpsOrderCtx(ctx context.Context, order models.OrderRef) (*models.GenericResponse[any], error) {
+	path := models.V1PerpsOrdersPath + "/" + order.PathSegment()
+
+	res, err := newJsonClient[any, models.GenericResponse[any]](client, path).
+		WithHeaderFunc(client.signCall).DeleteCtx(ctx, nil)
+
+	if err != nil {
+		return res, fmt.Errorf("error in http req perps delete order: %w", err)
+	}
+	if !res.Success {
+		return res, fmt.Errorf("bad request perps delete order %s: %w", order.PathSegment(), newResponseError("DELETE", path, res.Error))
+	}
+
+	return res, nil
+}
+
+func (client *ApiClient) GetPerpsFills(params models.FillParams) (*models.V1PageRes[models.ApiFill], error) {
+	return client.GetPerpsFillsCtx(context.Background(), params)
+}
+
+func (client *ApiClient) GetPerpsFillsCtx(ctx context.Context, params models.FillParams) (*models.V1PageRes[models.ApiFill], error) {
+	path := models.V1PerpsFillsPath
+	path += params.GetFillPathParams()
+
+	res, err := newJsonClient[any, models.V1PageRes[models.ApiFill]](client, path).
+		WithHeaderFunc(client.signCall).GetCtx(ctx, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("error in http req perps get fills: %w", err)
+	}
+
+	return res, err
+}
+
+func (client *ApiClient) GetPerpsPositions() (*models.GenericResponse[[]models.Position], error) {
+	return client.GetPerpsPositionsCtx(context.Background())
+}
+
+func (client *ApiClient) GetPerpsPositionsCtx(ctx context.Context) (*models.GenericResponse[[]models.Position], error) {
+	path := models.V1PerpsPositionsPath
+
+	res, err := newJsonClient[any, models.GenericResponse[[]models.Position]](client, path).
+		WithHeaderFunc(client.signCall).GetCtx(ctx, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("error in http req perps get positions: %w", err)
+	}
+	if !res.Success {
+		return res, fmt.Errorf("bad request perps get positions: %w", newResponseError("GET", path, res.Error))
+	}
+
+	return res, nil
+}
+
+func (client *ApiClient) GetPerpsPosition(market models.Market) (*models.GenericResponse[models.Position], error) {
+	return client.GetPerpsPositionCtx(context.Background(), market)
+}
+
+// GetPerpsPositionCtx returns the position in market, flat if there is none.
+func (client *ApiClient) GetPerpsPositionCtx(ctx context.Context, market models.Market) (*models.GenericResponse[models.Position], error) {
+	path := models.V1PerpsPositionsPath + "/" + string(market)
+
+	res, err := newJsonClient[any, models.GenericResponse[models.Position]](client, path).
+		WithHeaderFunc(client.signCall).GetCtx(ctx, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("error in http req perps get position: %w", err)
+	}
+	if !res.Success {
+		return res, fmt.Errorf("bad request perps get position %s: %w", market, newResponseError("GET", path, res.Error))
+	}
+
+	return res, nil
+}
+
+func (client *ApiClient) GetPerpsLeverage(market models.Market) (*models.GenericResponse[models.Leverage], error) {
+	return client.GetPerpsLeverageCtx(context.Background(), market)
+}
+
+func (client *ApiClient) GetPerpsLeverageCtx(ctx context.Context, market models.Market) (*models.GenericResponse[models.Leverage], error) {
+	path := models.V1PerpsLeveragePath + "?market=" + string(market)
+
+	res, err := newJsonClient[any, models.GenericResponse[models.Leverage]](client, path).
+		WithHeaderFunc(client.signCall).GetCtx(ctx, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("error in http req perps get leverage: %w", err)
+	}
+	if !res.Success {
+		return res, fmt.Errorf("bad request perps get leverage %s: %w", market, newResponseError("GET", path, res.Error))
+	}
+
+	return res, nil
+}
+
+func (client *ApiClient) SetPerpsLeverage(market models.Market, leverage decimal.Decimal) (*models.GenericResponse[models.Leverage], error) {
+	return client.SetPerpsLeverageCtx(context.Background(), market, leverage)
+}
+
+// SetPerpsLeverageCtx sets the leverage of market, bounded by MarginRequirements.MaxLeverage. It applies to the
+// orders placed afterwards.
+func (client *ApiClient) SetPerpsLeverageCtx(ctx context.Context, market models.Market, leverage decimal.Decimal) (*models.GenericResponse[models.Leverage], error) {
+	path := models.V1PerpsLeveragePath
+	req := models.SetLeverageReq{Market: market, Leverage: leverage}
+
+	res, err := newJsonClient[models.SetLeverageReq, models.GenericResponse[models.Leverage]](client, path).
+		WithHeaderFunc(client.signCall).PostCtx(ctx, req)
+
+	if err != nil {
+		return res, fmt.Errorf("error in http req perps set leverage: %w", err)
+	}
+	if !res.Success {
+		return res, fmt.Errorf("bad request perps set leverage %s: %w", market, newResponseError("POST", path, res.Error))
+	}
+
+	return res, nil
+}
+
+func (client *ApiClient) GetPerpsMarginRequirements(market models.Market) (*models.GenericResponse[models.MarginRequirements], error) {
+	return client.GetPerpsMarginRequirementsCtx(context.Background(), market)
+}
+
+func (client *ApiClient) GetPerpsMarginRequirementsCtx(ctx context.Context, market models.Market) (*models.GenericResponse[models.MarginRequirements], error) {
+	path := models.V1PerpsMarginRequirementsPath + "?market=" + string(market)
+
+	res, err := newJsonClient[any, models.GenericResponse[models.MarginRequirements]](client, path).
+		WithHeaderFunc(client.signCall).GetCtx(ctx, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("error in http req perps get margin requirements: %w", err)
+	}
+	if !res.Success {
+		return res, fmt.Errorf("bad request perps get margin requirements %s: %w", market, newResponseError("GET", path, res.Error))
+	}
+
+	return res, nil
+}
+
+func (client *ApiClient) GetPerpsPrices(market models.Market) (*models.GenericResponse[models.PerpsPrices], error) {
+	return client.GetPerpsPricesCtx(context.Background(), market)
+}
+
+// GetPerpsPricesCtx returns the mark and index price of market.
+func (client *ApiClient) GetPerpsPricesCtx(ctx context.Context, market models.Market) (*models.GenericResponse[models.PerpsPrices], error) {
+	path := models.V1PerpsPricesPath + "?market=" + string(market)
+
+	res, err := newJsonClient[any, models.GenericResponse[models.PerpsPrices]](client, path).
+		WithHeaderFunc(client.signCall).GetCtx(ctx, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("error in http req perps get prices: %w", err)
+	}
+	if !res.Success {
+		return res, fmt.Errorf("bad request perps get prices %s: %w", market, newResponseError("GET", path, res.Error))
+	}
+
+	return res, nil
+}
+
+func (client *ApiClient) GetPerpsFundingRates(market models.Market) (*models.GenericResponse[[]models.FundingRate], error) {
+	return client.GetPerpsFundingRatesCtx(context.Background(), market)
+}
+
+// GetPerpsFundingRatesCtx returns the recent funding rates of market, latest first.
+func (client *ApiClient) GetPerpsFundingRatesCtx(ctx context.Context, market models.Market) (*models.GenericResponse[[]models.FundingRate], error) {
+	path := models.V1PerpsFundingRatesPath + "?market=" + string(market)
+
+	res, err := newJsonClient[any, models.GenericResponse[[]models.FundingRate]](client, path).
+		WithHeaderFunc(client.signCall).GetCtx(ctx, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("error in http req perps get funding rates: %w", err)
+	}
+	if !res.Success {
+		return res, fmt.Errorf("bad request perps get funding rates %s: %w", market, newResponseError("GET", path, res.Error))
+	}
+
+	return res, nil
+}
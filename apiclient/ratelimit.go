@@ -0,0 +1,254 @@
+package apiclient
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Enclave-Markets/enclave-go/models"
+	"golang.org/x/time/rate"
+)
+
+// RateLimitBucket identifies a group of endpoints sharing a client-side rate limit.
+type RateLimitBucket string
+
+const (
+	// Spot and perps order placement and replacement.
+	BucketOrderEntry RateLimitBucket = "orderEntry"
+
+	// Order cancels, single and cancel-all.
+	BucketCancels RateLimitBucket = "cancels"
+
+	// Public market data reads: depth, markets, status, perps prices and funding rates.
+	BucketMarketData RateLimitBucket = "marketData"
+
+	// Every other request, e.g. order, fill and balance queries.
+	BucketOther RateLimitBucket = "other"
+)
+
+var rateLimitBuckets = []RateLimitBucket{BucketOrderEntry, BucketCancels, BucketMarketData, BucketOther}
+
+// RateLimit is a token bucket refilled at PerSecond tokens per second holding up to Burst tokens. The zero value
+// does not limit.
+type RateLimit struct {
+	PerSecond float64
+	Burst     int
+}
+
+// RateLimitConfig configures the client-side rate limiter of an ApiClient.
+type RateLimitConfig struct {
+	OrderEntry RateLimit
+	Cancels    RateLimit
+	MarketData RateLimit
+	Other      RateLimit
+
+	// Pause applied to a bucket after a 429 response without a usable Retry-After header. Defaults to 1 second.
+	DefaultPause time.Duration
+}
+
+// RateLimitState is a snapshot of a bucket, so strategies can throttle themselves before the exchange does.
+type RateLimitState struct {
+	Bucket RateLimitBucket
+
+	// Tokens currently available, negative when requests are queued ahead and +Inf for an unlimited bucket.
+	Tokens float64
+
+	// Number of requests waiting on the bucket.
+	Queued int
+
+	// Set while the bucket is paused after a 429 response.
+	PausedUntil time.Time
+}
+
+// WithRateLimits enables client-side rate limiting.
+func WithRateLimits(config RateLimitConfig) Option {
+	return func(cfg *clientConfig) {
+		cfg.rateLimits = &config
+	}
+}
+
+// RateLimiter holds the per-endpoint buckets of an ApiClient. It is safe for concurrent use.
+type RateLimiter struct {
+	buckets      map[RateLimitBucket]*limitBucket
+	defaultPause time.Duration
+}
+
+type limitBucket struct {
+	limiter *rate.Limiter
+	queued  atomic.Int64
+
+	mu          sync.Mutex
+	pausedUntil time.Time
+}
+
+func NewRateLimiter(config RateLimitConfig) *RateLimiter {
+	limits := map[RateLimitBucket]RateLimit{
+		BucketOrderEntry: config.OrderEntry,
+		BucketCancels:    config.Cancels,
+		BucketMarketData: config.MarketData,
+		BucketOther:      config.Other,
+	}
+
+	rl := &RateLimiter{
+		buckets:      map[RateLimitBucket]*limitBucket{},
+		defaultPause: config.DefaultPause,
+	}
+	if rl.defaultPause <= 0 {
+		rl.defaultPause = time.Second
+	}
+
+	for bucket, limit := range limits {
+		limiter := rate.NewLimiter(rate.Inf, 0)
+		if limit.PerSecond > 0 {
+			limiter = rate.NewLimiter(rate.Limit(limit.PerSecond), max(limit.Burst, 1))
+		}
+		rl.buckets[bucket] = &limitBucket{limiter: limiter}
+	}
+
+	return rl
+}
+
+// BucketFor returns the bucket a request is counted against.
+func BucketFor(method string, path string) RateLimitBucket {
+	path, _, _ = strings.Cut(path, "?")
+
+	switch {
+	case method == http.MethodPost &&
+		(strings.HasPrefix(path, models.V1SpotOrdersPath) || strings.HasPrefix(path, models.V1PerpsOrdersPath)):
+		return BucketOrderEntry
+	case method == http.MethodDelete &&
+		(strings.HasPrefix(path, models.V1SpotOrdersPath) || strings.HasPrefix(path, models.V1PerpsOrdersPath)):
+		return BucketCancels
+	case method == http.MethodGet && (path == models.V1SpotDepthPath || path == models.V1MarketsPath ||
+		path == models.StatusPath || path == models.HelloPath ||
+		path == models.V1PerpsPricesPath || path == models.V1PerpsFundingRatesPath):
+		return BucketMarketData
+	default:
+		return BucketOther
+	}
+}
+
+// Wait blocks until the bucket of the request has a token and is not paused, or the context is done.
+func (rl *RateLimiter) Wait(ctx context.Context, method string, path string) error {
+	return rl.WaitN(ctx, method, path, 1)
+}
+
+// WaitN is like Wait but takes n tokens, e.g. one per order of a batch request. More tokens than the bucket's burst
+// are taken in burst-sized chunks, so a large batch waits for the bucket to refill instead of failing.
+func (rl *RateLimiter) WaitN(ctx context.Context, method string, path string, n int) error {
+	b := rl.buckets[BucketFor(method, path)]
+	b.queued.Add(1)
+	defer b.queued.Add(-1)
+
+	for {
+		b.mu.Lock()
+		pause := time.Until(b.pausedUntil)
+		b.mu.Unlock()
+		if pause <= 0 {
+			break
+		}
+
+		timer := time.NewTimer(pause)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if b.limiter.Limit() == rate.Inf {
+		return nil
+	}
+	for n > 0 {
+		chunk := min(n, b.limiter.Burst())
+		if err := b.limiter.WaitN(ctx, chunk); err != nil {
+			return err
+		}
+		n -= chunk
+	}
+	return nil
+}
+
+// Pause stops the bucket from handing out tokens for d.
+func (rl *RateLimiter) Pause(bucket RateLimitBucket, d time.Duration) {
+	b, ok := rl.buckets[bucket]
+	if !ok {
+		return
+	}
+
+	until := time.Now().Add(d)
+	b.mu.Lock()
+	if until.After(b.pausedUntil) {
+		b.pausedUntil = until
+	}
+	b.mu.Unlock()
+}
+
+// observe pauses the request's bucket when the exchange answered 429, honouring Retry-After.
+func (rl *RateLimiter) observe(method string, path string, statusCode int, header http.Header) {
+	if statusCode != http.StatusTooManyRequests {
+		return
+	}
+
+	pause, ok := parseRetryAfter(header.Get("Retry-After"))
+	if !ok {
+		pause = rl.defaultPause
+	}
+	rl.Pause(BucketFor(method, path), pause)
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(time.Until(at), 0), true
+	}
+	return 0, false
+}
+
+// State returns a snapshot of bucket.
+func (rl *RateLimiter) State(bucket RateLimitBucket) RateLimitState {
+	b, ok := rl.buckets[bucket]
+	if !ok {
+		return RateLimitState{Bucket: bucket}
+	}
+
+	b.mu.Lock()
+	pausedUntil := b.pausedUntil
+	b.mu.Unlock()
+	if !pausedUntil.After(time.Now()) {
+		pausedUntil = time.Time{}
+	}
+
+	tokens := b.limiter.Tokens()
+	if b.limiter.Limit() == rate.Inf {
+		tokens = math.Inf(1)
+	}
+
+	return RateLimitState{
+		Bucket:      bucket,
+		Tokens:      tokens,
+		Queued:      int(b.queued.Load()),
+		PausedUntil: pausedUntil,
+	}
+}
+
+// States returns a snapshot of every bucket.
+func (rl *RateLimiter) States() []RateLimitState {
+	states := make([]RateLimitState, 0, len(rateLimitBuckets))
+	for _, bucket := range rateLimitBuckets {
+		states = append(states, rl.State(bucket))
+	}
+	return states
+}
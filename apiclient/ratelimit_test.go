@@ -0,0 +1,138 @@
+package apiclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Enclave-Markets/enclave-go/models"
+)
+
+func TestBucketFor(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   RateLimitBucket
+	}{
+		{http.MethodPost, models.V1SpotOrdersPath, BucketOrderEntry},
+		{http.MethodPost, models.V1SpotOrdersPath + "/o1/replace", BucketOrderEntry},
+		{http.MethodPost, models.V1PerpsOrdersPath, BucketOrderEntry},
+		{http.MethodDelete, models.V1SpotOrdersPath + "/o1", BucketCancels},
+		{http.MethodDelete, models.V1SpotOrdersPath + "?market=AVAX-USDC", BucketCancels},
+		{http.MethodGet, models.V1SpotOrdersPath + "/o1", BucketOther},
+		{http.MethodGet, models.V1SpotDepthPath + "?market=AVAX-USDC", BucketMarketData},
+		{http.MethodGet, models.StatusPath, BucketMarketData},
+		{http.MethodGet, models.V0GetBalancePath, BucketOther},
+	}
+
+	for _, test := range tests {
+		if got := BucketFor(test.method, test.path); got != test.want {
+			t.Errorf("BucketFor(%s, %s) = %s, want %s", test.method, test.path, got, test.want)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"3", 3 * time.Second, true},
+		{"0", 0, true},
+		{"-1", 0, false},
+		{"soon", 0, false},
+		{time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), 0, true},
+	}
+
+	for _, test := range tests {
+		got, ok := parseRetryAfter(test.value)
+		if got != test.want || ok != test.ok {
+			t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", test.value, got, ok, test.want, test.ok)
+		}
+	}
+
+	// A date is relative to now, rounded to the second
+	got, ok := parseRetryAfter(time.Now().Add(5 * time.Second).UTC().Format(http.TimeFormat))
+	if !ok || got <= 3*time.Second || got > 5*time.Second {
+		t.Errorf("parseRetryAfter of a date 5s away = %v, %v", got, ok)
+	}
+}
+
+func TestRateLimiterPause(t *testing.T) {
+	rl := NewRateLimiter(RateLimitConfig{})
+	ctx := context.Background()
+
+	if state := rl.State(BucketCancels); !math.IsInf(state.Tokens, 1) || !state.PausedUntil.IsZero() {
+		t.Fatalf("unlimited bucket state %+v", state)
+	}
+
+	rl.Pause(BucketCancels, 50*time.Millisecond)
+	// A shorter pause does not shorten the current one
+	rl.Pause(BucketCancels, time.Millisecond)
+	if state := rl.State(BucketCancels); state.PausedUntil.IsZero() {
+		t.Fatalf("bucket not paused: %+v", state)
+	}
+
+	// Other buckets are not paused
+	start := time.Now()
+	if err := rl.Wait(ctx, http.MethodPost, models.V1SpotOrdersPath); err != nil || time.Since(start) > 25*time.Millisecond {
+		t.Fatalf("order entry waited %v on a cancels pause: %v", time.Since(start), err)
+	}
+
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := rl.Wait(short, http.MethodDelete, models.V1SpotOrdersPath+"/o1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("wait on a paused bucket: got %v, want the context deadline", err)
+	}
+
+	if err := rl.Wait(ctx, http.MethodDelete, models.V1SpotOrdersPath+"/o1"); err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+	if waited := time.Since(start); waited < 45*time.Millisecond {
+		t.Fatalf("waited %v, want the end of the 50ms pause", waited)
+	}
+}
+
+func TestRateLimiterWaitN(t *testing.T) {
+	rl := NewRateLimiter(RateLimitConfig{OrderEntry: RateLimit{PerSecond: 100, Burst: 5}})
+	ctx := context.Background()
+
+	// 12 tokens from a burst of 5 take 7 refills, about 70ms, instead of failing
+	start := time.Now()
+	if err := rl.WaitN(ctx, http.MethodPost, models.V1SpotOrdersPath, 12); err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+	if waited := time.Since(start); waited < 50*time.Millisecond {
+		t.Fatalf("waited %v for 12 tokens of a bucket of 5 at 100/s", waited)
+	}
+}
+
+// TestRateLimiter429 checks that a 429 pauses the bucket of the request for its Retry-After.
+func TestRateLimiter429(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = io.WriteString(w, `{"success":false,"error":"rate limit exceeded"}`)
+	}))
+	defer server.Close()
+	client := NewApiClientWithOptions(server.URL, WithRateLimits(RateLimitConfig{DefaultPause: time.Hour}))
+
+	if _, err := client.CancelSpotOrder(models.OrderID("o1")); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("got %v, want ErrRateLimited", err)
+	}
+
+	state := client.RateLimiter().State(BucketCancels)
+	if until := time.Until(state.PausedUntil); until <= 0 || until > time.Second {
+		t.Fatalf("cancels paused for %v, want the 1s of Retry-After", until)
+	}
+	if state := client.RateLimiter().State(BucketOrderEntry); !state.PausedUntil.IsZero() {
+		t.Fatalf("order entry paused by a cancel's 429: %+v", state)
+	}
+}
@@ -0,0 +1,144 @@
+package apiclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Enclave-Markets/enclave-go/models"
+)
+
+// replaceSettleInterval is how often the replaced order is polled while its cancel settles, for at most
+// replaceSettleTimeout.
+const (
+	replaceSettleInterval = 50 * time.Millisecond
+	replaceSettleTimeout  = 10 * time.Second
+)
+
+func (client *ApiClient) ReplaceSpotOrder(order models.OrderRef, req models.ReplaceOrderReq) (*models.ReplaceOrderRes, error) {
+	return client.ReplaceSpotOrderCtx(context.Background(), order, req)
+}
+
+// ReplaceSpotOrderCtx moves an order, addressed by its exchange OrderID or its ClientOrderID, to a new price and
+// size. The new order's client order ID defaults to the next
+// of the old one's, see models.NextClientOrderID.
+//
+// The native amend endpoint is used when the server has one. Otherwise the old order is canceled, its final state
+// awaited, and only then the new order placed, so both are never resting at once. If the old order filled, fully or
+// partly, while it was being canceled, no order is placed and the error matches ErrOrderFilled, res.Old holds the
+// fill. Fills made before the call are not deducted from the new size, check res.Old.FilledQuantity.
+func (client *ApiClient) ReplaceSpotOrderCtx(ctx context.Context, order models.OrderRef, req models.ReplaceOrderReq) (*models.ReplaceOrderRes, error) {
+	old, err := client.GetSpotOrderCtx(ctx, order)
+	if err != nil {
+		return nil, fmt.Errorf("error replacing spot order %s: %w", order.PathSegment(), err)
+	}
+	orderId := old.Result.OrderID
+	if old.Result.State == models.FullyFilled {
+		return &models.ReplaceOrderRes{Old: old.Result}, fmt.Errorf("error replacing spot order %s: %w", orderId, ErrOrderFilled)
+	}
+	if req.ClientOrderID == "" {
+		req.ClientOrderID = models.NextClientOrderID(old.Result.ClientOrderID)
+	}
+
+	if !client.amendUnsupported.Load() {
+		res, err := client.amendSpotOrder(ctx, orderId, req)
+		if !isMissingEndpoint(err) {
+			return res, err
+		}
+		client.amendUnsupported.Store(true)
+	}
+
+	return client.cancelReplaceSpotOrder(ctx, old.Result, req)
+}
+
+func (client *ApiClient) amendSpotOrder(ctx context.Context, orderId models.OrderID, req models.ReplaceOrderReq) (*models.ReplaceOrderRes, error) {
+	path := models.V1SpotOrdersPath + "/" + string(orderId) + "/replace"
+
+	res, err := newJsonClient[models.ReplaceOrderReq, models.GenericResponse[models.ReplaceOrderRes]](client, path).
+		WithHeaderFunc(client.signCall).PostCtx(ctx, req)
+
+	if err != nil {
+		return nil, fmt.Errorf("error in http req spot replace order: %w", err)
+	}
+	if !res.Success {
+		return &res.Result, fmt.Errorf("bad request spot replace order %s: %w", orderId, newResponseError("POST", path, res.Error))
+	}
+
+	return &res.Result, nil
+}
+
+// cancelReplaceSpotOrder replaces old by canceling it and placing the new order once the cancel settled. A new order
+// the market gate rejects is rejected before old is canceled, so the quote is not pulled without a replacement.
+func (client *ApiClient) cancelReplaceSpotOrder(ctx context.Context, old models.ApiOrder, req models.ReplaceOrderReq) (*models.ReplaceOrderRes, error) {
+	next := models.AddOrderReq{
+		Side:          old.Side,
+		Price:         req.Price,
+		Size:          req.Size,
+		Market:        old.Market,
+		ClientOrderID: req.ClientOrderID,
+		Type:          old.Type,
+		TimeInForce:   old.TimeInForce,
+		PostOnly:      req.PostOnly,
+	}
+
+	client.mu.RLock()
+	gate := client.marketGate
+	client.mu.RUnlock()
+	if gate != nil {
+		if err := gate.CheckOrder(next); err != nil {
+			return &models.ReplaceOrderRes{Old: old}, fmt.Errorf("error replacing spot order %s: %w", old.OrderID, err)
+		}
+	}
+
+	// An order that is gone or filled already has a final state to check, any other failure leaves it resting
+	_, err := client.CancelSpotOrderCtx(ctx, old.OrderID)
+	if err != nil && !errors.Is(err, ErrOrderNotFound) && !errors.Is(err, ErrOrderFilled) {
+		return &models.ReplaceOrderRes{Old: old}, fmt.Errorf("error canceling replaced spot order %s: %w", old.OrderID, err)
+	}
+
+	settleCtx, cancel := context.WithTimeout(ctx, replaceSettleTimeout)
+	final, err := client.awaitFinalSpotOrder(settleCtx, old.OrderID)
+	cancel()
+	if err != nil {
+		return &models.ReplaceOrderRes{Old: old}, err
+	}
+
+	res := &models.ReplaceOrderRes{Old: *final}
+	switch {
+	case final.State == models.FullyFilled:
+		return res, fmt.Errorf("error replacing spot order %s: %w", old.OrderID, ErrOrderFilled)
+	case final.State != models.Canceled:
+		return res, fmt.Errorf("error replacing spot order %s: ended %s instead of canceled", old.OrderID, final.State)
+	case final.FilledQuantity.GreaterThan(old.FilledQuantity):
+		return res, fmt.Errorf("error replacing spot order %s: filled %s more while canceling: %w", old.OrderID,
+			final.FilledQuantity.Sub(old.FilledQuantity), ErrOrderFilled)
+	}
+
+	placed, err := client.AddSpotOrderCtx(ctx, next)
+	if err != nil {
+		return res, fmt.Errorf("error placing replacing order of %s: %w", old.OrderID, err)
+	}
+
+	res.New = &placed.Result
+	return res, nil
+}
+
+// awaitFinalSpotOrder polls the order until it is canceled or filled, or ctx is done.
+func (client *ApiClient) awaitFinalSpotOrder(ctx context.Context, orderId models.OrderID) (*models.ApiOrder, error) {
+	for {
+		res, err := client.GetSpotOrderCtx(ctx, orderId)
+		if err != nil {
+			return nil, err
+		}
+		if res.Result.State != models.New && res.Result.State != models.Open {
+			return &res.Result, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("spot order %s still %s: %w", orderId, res.Result.State, ctx.Err())
+		case <-time.After(replaceSettleInterval):
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package apiclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/Enclave-Markets/enclave-go/models"
+	"github.com/shopspring/decimal"
+)
+
+func (f *fakeExchange) update(id models.OrderID, change func(order *models.ApiOrder)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	change(f.orders[id])
+}
+
+func TestReplaceSpotOrder(t *testing.T) {
+	req := models.ReplaceOrderReq{Price: decimal.NewFromInt(12), Size: decimal.NewFromInt(2)}
+	replacePath := models.V1SpotOrdersPath + "/o1/replace"
+
+	t.Run("amend endpoint", func(t *testing.T) {
+		exchange, server := newFakeExchange(t)
+		var got models.ReplaceOrderReq
+		exchange.route = func(w http.ResponseWriter, r *http.Request) bool {
+			if r.URL.Path != replacePath {
+				return false
+			}
+			if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return true
+			}
+			writeResult(w, models.ReplaceOrderRes{Old: models.ApiOrder{OrderID: "o1"}, New: &models.ApiOrder{OrderID: "o2"}})
+			return true
+		}
+		exchange.add(limitOrder("AVAX-USDC", models.Bid, "quote"))
+		client := NewApiClient(server.URL)
+
+		res, err := client.ReplaceSpotOrder(models.OrderID("o1"), req)
+		if err != nil {
+			t.Fatalf("replace: %v", err)
+		}
+		if res.New == nil || res.New.OrderID != "o2" || got.ClientOrderID != "quote-r1" {
+			t.Fatalf("got %+v sent %+v, want o2 replacing with client id quote-r1", res, got)
+		}
+		if n := exchange.received(http.MethodDelete, models.V1SpotOrdersPath+"/o1"); n != 0 {
+			t.Fatalf("old order canceled %d times by an amend", n)
+		}
+	})
+
+	t.Run("cancel then place", func(t *testing.T) {
+		exchange, server := newFakeExchange(t)
+		exchange.add(limitOrder("AVAX-USDC", models.Bid, "quote"))
+		client := NewApiClient(server.URL)
+
+		res, err := client.ReplaceSpotOrder(models.ClientOrderID("quote"), req)
+		if err != nil {
+			t.Fatalf("replace: %v", err)
+		}
+		if res.Old.State != models.Canceled || res.New == nil || res.New.ClientOrderID != "quote-r1" ||
+			!res.New.Price.Equal(req.Price) || res.New.Side != models.Bid {
+			t.Fatalf("unexpected result %+v", res)
+		}
+
+		// The missing amend route is remembered, a gone order is not mistaken for it
+		if _, err := client.ReplaceSpotOrder(models.OrderID("unknown"), req); !errors.Is(err, ErrOrderNotFound) {
+			t.Fatalf("replace unknown order: got %v, want ErrOrderNotFound", err)
+		}
+		if _, err := client.ReplaceSpotOrder(models.OrderID(res.New.OrderID), req); err != nil {
+			t.Fatalf("second replace: %v", err)
+		}
+		if n := exchange.received(http.MethodPost, replacePath); n != 1 {
+			t.Fatalf("sent %d amends, want 1", n)
+		}
+	})
+
+	t.Run("filled while canceling", func(t *testing.T) {
+		exchange, server := newFakeExchange(t)
+		exchange.add(limitOrder("AVAX-USDC", models.Bid, "quote"))
+		exchange.route = func(w http.ResponseWriter, r *http.Request) bool {
+			if r.Method == http.MethodDelete && r.URL.Path == models.V1SpotOrdersPath+"/o1" {
+				exchange.update("o1", func(order *models.ApiOrder) { order.FilledQuantity = decimal.NewFromInt(1) })
+			}
+			return false
+		}
+		client := NewApiClient(server.URL)
+
+		res, err := client.ReplaceSpotOrder(models.OrderID("o1"), req)
+		if !errors.Is(err, ErrOrderFilled) {
+			t.Fatalf("got %v, want ErrOrderFilled", err)
+		}
+		if res.New != nil || !res.Old.FilledQuantity.Equal(decimal.NewFromInt(1)) {
+			t.Fatalf("unexpected result %+v", res)
+		}
+		if n := exchange.received(http.MethodPost, models.V1SpotOrdersPath); n != 0 {
+			t.Fatalf("placed %d orders after a fill", n)
+		}
+	})
+
+	t.Run("cancel rejected", func(t *testing.T) {
+		exchange, server := newFakeExchange(t)
+		exchange.add(limitOrder("AVAX-USDC", models.Bid, "quote"))
+		exchange.route = func(w http.ResponseWriter, r *http.Request) bool {
+			if r.Method != http.MethodDelete {
+				return false
+			}
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = io.WriteString(w, `{"success":false,"error":"rate limit exceeded"}`)
+			return true
+		}
+		client := NewApiClient(server.URL)
+
+		if _, err := client.ReplaceSpotOrder(models.OrderID("o1"), req); !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("got %v, want ErrRateLimited", err)
+		}
+		if n := exchange.received(http.MethodGet, models.V1SpotOrdersPath+"/o1"); n != 1 {
+			t.Fatalf("polled the order %d times after a rejected cancel, want only the initial lookup", n)
+		}
+		if state := exchange.state("o1"); state != models.Open {
+			t.Fatalf("old order is %s", state)
+		}
+	})
+
+	t.Run("market gate", func(t *testing.T) {
+		exchange, server := newFakeExchange(t)
+		exchange.add(limitOrder("AVAX-USDC", models.Bid, "quote"))
+		exchange.route = func(w http.ResponseWriter, r *http.Request) bool {
+			if r.URL.Path != models.StatusPath {
+				return false
+			}
+			_, _ = io.WriteString(w, `{"marketStatuses":{"AVAX-USDC":"cancelOnly"}}`)
+			return true
+		}
+		client := NewApiClient(server.URL)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		if _, err := client.WatchMarketStatus(ctx, MarketStatusWatchConfig{GateOrders: true}); err != nil {
+			t.Fatalf("watch market status: %v", err)
+		}
+
+		if _, err := client.ReplaceSpotOrder(models.OrderID("o1"), req); !errors.Is(err, ErrMarketDisabled) {
+			t.Fatalf("got %v, want ErrMarketDisabled", err)
+		}
+		if state := exchange.state("o1"); state != models.Open {
+			t.Fatalf("old order is %s after a gated replace", state)
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package apiclient
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"slices"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy configures how requests that fail transiently are retried. Only idempotent requests (GET and DELETE)
+// are retried by the transport. AddSpotOrder is retried only when the order has a ClientOrderID, see
+// ApiClient.AddSpotOrderCtx.
+type RetryPolicy struct {
+	// Total number of attempts, including the first one. Values below 2 disable retries.
+	MaxAttempts int
+
+	// Backoff before the first retry. It doubles on every further retry up to MaxBackoff, zero means no cap.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// Response statuses that are worth retrying. Connection failures, timeouts and resets are always retried.
+	RetryableStatusCodes []int
+}
+
+// DefaultRetryPolicy retries up to 3 attempts on 5xx gateway errors and connection failures.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     2 * time.Second,
+		RetryableStatusCodes: []int{
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// WithRetryPolicy enables retries on the client.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(cfg *clientConfig) {
+		cfg.retryPolicy = &policy
+	}
+}
+
+func isIdempotent(method string) bool {
+	return method == http.MethodGet || method == http.MethodDelete || method == http.MethodHead
+}
+
+// canRetry reports whether attempt, counted from 0, may be followed by another one.
+func (p *RetryPolicy) canRetry(attempt int) bool {
+	return p != nil && attempt+1 < p.MaxAttempts
+}
+
+// isRetryable reports whether err is a transient failure: a retryable status or a network error. Context
+// cancellation is never retryable, and a timeout of the request's own context ends the retry loop on its backoff wait.
+func (p *RetryPolicy) isRetryable(err error) bool {
+	if p == nil || err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return slices.Contains(p.RetryableStatusCodes, apiErr.StatusCode)
+	}
+
+	return isNetworkError(err)
+}
+
+// isNetworkError reports whether err is a connection failure, timeout or reset worth retrying. Certificate and
+// malformed request errors fail the same way on every attempt.
+func isNetworkError(err error) bool {
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidCertErr x509.CertificateInvalidError
+	if errors.As(err, &certErr) || errors.As(err, &unknownAuthorityErr) || errors.As(err, &hostnameErr) ||
+		errors.As(err, &invalidCertErr) {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// backoff returns the jittered wait before the retry following attempt, counted from 0.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.InitialBackoff
+	for i := 0; i < attempt && d < math.MaxInt64/2; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+
+	// Pick uniformly in [d/2, d] so clients that failed together do not retry together.
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// wait sleeps for the backoff of attempt or until the context is done.
+func (p *RetryPolicy) wait(ctx context.Context, attempt int) error {
+	timer := time.NewTimer(p.backoff(attempt))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
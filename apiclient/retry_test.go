@@ -0,0 +1,180 @@
+package apiclient
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Enclave-Markets/enclave-go/models"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		initial  time.Duration
+		max      time.Duration
+		attempt  int
+		min, top time.Duration
+	}{
+		{"first retry", 100 * time.Millisecond, 2 * time.Second, 0, 50 * time.Millisecond, 100 * time.Millisecond},
+		{"doubles", 100 * time.Millisecond, 2 * time.Second, 2, 200 * time.Millisecond, 400 * time.Millisecond},
+		{"capped", 100 * time.Millisecond, 2 * time.Second, 10, time.Second, 2 * time.Second},
+		{"no cap", 100 * time.Millisecond, 0, 4, 800 * time.Millisecond, 1600 * time.Millisecond},
+		{"no cap does not overflow", 100 * time.Millisecond, 0, 100, time.Duration(1 << 61), time.Duration(1<<63 - 1)},
+		{"no backoff", 0, time.Second, 3, 0, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			policy := &RetryPolicy{InitialBackoff: test.initial, MaxBackoff: test.max}
+			for i := 0; i < 100; i++ {
+				if d := policy.backoff(test.attempt); d < test.min || d > test.top {
+					t.Fatalf("backoff %v, want within [%v, %v]", d, test.min, test.top)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryPolicyIsRetryable(t *testing.T) {
+	policy := &RetryPolicy{RetryableStatusCodes: []int{http.StatusServiceUnavailable}}
+	tests := []struct {
+		name   string
+		policy *RetryPolicy
+		err    error
+		want   bool
+	}{
+		{"no policy", nil, io.EOF, false},
+		{"no error", policy, nil, false},
+		{"canceled", policy, fmt.Errorf("wrapped: %w", context.Canceled), false},
+		{"retryable status", policy, &APIError{StatusCode: http.StatusServiceUnavailable}, true},
+		{"other status", policy, &APIError{StatusCode: http.StatusBadRequest}, false},
+		{"connection refused", policy, &net.OpError{Op: "dial", Err: errors.New("connection refused")}, true},
+		{"reset", policy, fmt.Errorf("read: %w", io.ErrUnexpectedEOF), true},
+		{"bad certificate", policy, fmt.Errorf("tls: %w", x509.UnknownAuthorityError{}), false},
+		{"other error", policy, errors.New("malformed request"), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.policy.isRetryable(test.err); got != test.want {
+				t.Fatalf("isRetryable(%v) = %v, want %v", test.err, got, test.want)
+			}
+		})
+	}
+}
+
+// TestAddSpotOrderRetry checks that a retried order with a client order id is placed once, whether or not the failed
+// attempt landed.
+func TestAddSpotOrderRetry(t *testing.T) {
+	policy := RetryPolicy{
+		MaxAttempts:          3,
+		InitialBackoff:       time.Millisecond,
+		RetryableStatusCodes: []int{http.StatusServiceUnavailable},
+	}
+	unavailable := func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = io.WriteString(w, `{"success":false,"error":"service unavailable"}`)
+	}
+	// failFirst answers the first order placement with a 503, after placing the order when landed is set.
+	failFirst := func(exchange *fakeExchange, landed bool) func(w http.ResponseWriter, r *http.Request) bool {
+		failed := false
+		return func(w http.ResponseWriter, r *http.Request) bool {
+			if r.Method != http.MethodPost || r.URL.Path != models.V1SpotOrdersPath || failed {
+				return false
+			}
+			failed = true
+			if landed {
+				var req models.AddOrderReq
+				_ = json.NewDecoder(r.Body).Decode(&req)
+				exchange.add(req)
+			}
+			unavailable(w)
+			return true
+		}
+	}
+
+	t.Run("failed attempt landed", func(t *testing.T) {
+		exchange, server := newFakeExchange(t)
+		exchange.route = failFirst(exchange, true)
+		client := NewApiClientWithOptions(server.URL, WithRetryPolicy(policy))
+
+		res, err := client.AddSpotOrder(limitOrder("AVAX-USDC", models.Bid, "quote"))
+		if err != nil {
+			t.Fatalf("add order: %v", err)
+		}
+		if res.Result.ClientOrderID != "quote" {
+			t.Fatalf("got %+v, want the order that landed", res.Result)
+		}
+		if n := exchange.received(http.MethodPost, models.V1SpotOrdersPath); n != 1 {
+			t.Fatalf("sent %d placements, want 1", n)
+		}
+	})
+
+	t.Run("failed attempt did not land", func(t *testing.T) {
+		exchange, server := newFakeExchange(t)
+		exchange.route = failFirst(exchange, false)
+		client := NewApiClientWithOptions(server.URL, WithRetryPolicy(policy))
+
+		if _, err := client.AddSpotOrder(limitOrder("AVAX-USDC", models.Bid, "quote")); err != nil {
+			t.Fatalf("add order: %v", err)
+		}
+		if n := exchange.received(http.MethodPost, models.V1SpotOrdersPath); n != 2 {
+			t.Fatalf("sent %d placements, want 2", n)
+		}
+		if len(exchange.orders) != 1 {
+			t.Fatalf("placed %d orders, want 1", len(exchange.orders))
+		}
+	})
+
+	t.Run("landed after the lookup", func(t *testing.T) {
+		exchange, server := newFakeExchange(t)
+		posts := 0
+		exchange.route = func(w http.ResponseWriter, r *http.Request) bool {
+			if r.Method != http.MethodPost || r.URL.Path != models.V1SpotOrdersPath {
+				return false
+			}
+			posts++
+			var req models.AddOrderReq
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			if posts == 1 {
+				unavailable(w)
+				return true
+			}
+			// The first attempt shows up only now, the resubmission is rejected as a duplicate
+			exchange.add(req)
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = io.WriteString(w, `{"success":false,"error":"duplicate client order id"}`)
+			return true
+		}
+		client := NewApiClientWithOptions(server.URL, WithRetryPolicy(policy))
+
+		res, err := client.AddSpotOrder(limitOrder("AVAX-USDC", models.Bid, "quote"))
+		if err != nil {
+			t.Fatalf("add order: %v", err)
+		}
+		if res.Result.ClientOrderID != "quote" || len(exchange.orders) != 1 {
+			t.Fatalf("got %+v with %d orders, want the single order placed", res.Result, len(exchange.orders))
+		}
+	})
+
+	t.Run("without client order id", func(t *testing.T) {
+		exchange, server := newFakeExchange(t)
+		exchange.route = failFirst(exchange, false)
+		client := NewApiClientWithOptions(server.URL, WithRetryPolicy(policy))
+
+		if _, err := client.AddSpotOrder(limitOrder("AVAX-USDC", models.Bid, "")); err == nil {
+			t.Fatalf("add order succeeded, want the 503")
+		}
+		if n := exchange.received(http.MethodPost, models.V1SpotOrdersPath); n != 1 {
+			t.Fatalf("sent %d placements of an order without client order id, want 1", n)
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package apiclient
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Signer computes the ENCLAVE-SIGN signature of requests for one API key. The payload is the timestamp, method,
+// path and body of the request concatenated, and the signature is its HMAC-SHA256 under the API secret.
+// Sign must return once ctx is done, it carries the deadline of the request being signed. Implementations must be safe
+// for concurrent use.
+type Signer interface {
+	KeyID() string
+	Sign(ctx context.Context, payload []byte) ([]byte, error)
+}
+
+// HmacSigner signs with an API secret held in process memory. It is the signer used by ApiClient.WithApiKey.
+type HmacSigner struct {
+	keyId     string
+	keySecret []byte
+}
+
+func NewHmacSigner(keyId, keySecret string) *HmacSigner {
+	return &HmacSigner{
+		keyId:     keyId,
+		keySecret: []byte(keySecret),
+	}
+}
+
+func (s *HmacSigner) KeyID() string {
+	return s.keyId
+}
+
+func (s *HmacSigner) Sign(ctx context.Context, payload []byte) ([]byte, error) {
+	mac := hmac.New(sha256.New, s.keySecret)
+	mac.Write(payload)
+	return mac.Sum(nil), nil
+}
+
+type signDaemonReq struct {
+	KeyID   string `json:"keyId"`
+	Payload string `json:"payload"`
+}
+
+type signDaemonRes struct {
+	Signature string `json:"signature"`
+	Error     string `json:"error,omitempty"`
+}
+
+// UnixSocketSigner delegates signing to a local daemon listening on a Unix socket, so the trading process never
+// holds the API secret. The daemon serves
+//
+//	POST /sign {"keyId": "<key id>", "payload": "<payload>"}
+//
+// and answers {"signature": "<hex HMAC-SHA256>"}, or a non 2xx status with {"error": "<message>"}.
+type UnixSocketSigner struct {
+	keyId      string
+	httpClient *http.Client
+}
+
+// NewUnixSocketSigner returns a signer for keyId backed by the daemon at socketPath. Each signature request is
+// abandoned after timeout, or earlier when the context of the request being signed is done. A zero timeout leaves
+// only the context to bound it.
+func NewUnixSocketSigner(socketPath string, keyId string, timeout time.Duration) *UnixSocketSigner {
+	dialer := &net.Dialer{}
+	return &UnixSocketSigner{
+		keyId: keyId,
+		httpClient: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return dialer.DialContext(ctx, "unix", socketPath)
+				},
+			},
+		},
+	}
+}
+
+func (s *UnixSocketSigner) KeyID() string {
+	return s.keyId
+}
+
+func (s *UnixSocketSigner) Sign(ctx context.Context, payload []byte) ([]byte, error) {
+	// The host is ignored, the transport always dials the socket
+	res, err := NewHttpJsonClient[signDaemonReq, signDaemonRes]("http://signer/sign").
+		WithHttpClient(s.httpClient).
+		PostCtx(ctx, signDaemonReq{KeyID: s.keyId, Payload: string(payload)})
+	if err != nil {
+		return nil, fmt.Errorf("error with signing daemon request: %w", err)
+	}
+	if res.Error != "" {
+		return nil, fmt.Errorf("signing daemon refused to sign: %s", res.Error)
+	}
+
+	sig, err := hex.DecodeString(res.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("signing daemon returned a malformed signature: %w", err)
+	}
+	return sig, nil
+}
@@ -0,0 +1,86 @@
+package apiclient
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// signDaemon serves the UnixSocketSigner protocol on a socket in a temporary directory, calling handler for every
+// signature request.
+func signDaemon(t *testing.T, handler http.HandlerFunc) string {
+	t.Helper()
+
+	socketPath := filepath.Join(t.TempDir(), "signer.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	server := &http.Server{Handler: handler}
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(func() { _ = server.Close() })
+	return socketPath
+}
+
+func TestUnixSocketSigner(t *testing.T) {
+	socketPath := signDaemon(t, func(w http.ResponseWriter, r *http.Request) {
+		var req signDaemonReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.KeyID != "key" {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(signDaemonRes{Error: "unknown key"})
+			return
+		}
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write([]byte(req.Payload))
+		_ = json.NewEncoder(w).Encode(signDaemonRes{Signature: hex.EncodeToString(mac.Sum(nil))})
+	})
+	signer := NewUnixSocketSigner(socketPath, "key", time.Second)
+
+	got, err := signer.Sign(context.Background(), []byte("payload"))
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	want, _ := NewHmacSigner("key", "secret").Sign(context.Background(), []byte("payload"))
+	if !hmac.Equal(got, want) {
+		t.Fatalf("got signature %x, want %x", got, want)
+	}
+}
+
+// TestUnixSocketSignerHungDaemon checks that a daemon that never answers cannot hold a request past its context, even
+// without a signer timeout.
+func TestUnixSocketSignerHungDaemon(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	socketPath := signDaemon(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	client := NewApiClientWithOptions("http://127.0.0.1:1", WithKeySigner(NewUnixSocketSigner(socketPath, "key", 0)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.AuthedHelloCtx(ctx)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("got %v, want the request deadline", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("request blocked on the signing daemon past its deadline")
+	}
+}
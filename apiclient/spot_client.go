@@ -1,145 +1,397 @@
 package apiclient
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
 
 	"github.com/Enclave-Markets/enclave-go/models"
 )
 
 func (client *ApiClient) AddSpotOrder(req models.AddOrderReq) (*models.GenericResponse[models.ApiOrder], error) {
+	return client.AddSpotOrderCtx(context.Background(), req)
+}
+
+// AddSpotOrderCtx places an order. When the client has a retry policy and the order has a ClientOrderID, transient
+// failures are retried: before resubmitting, the order is looked up by its client ID so that an order which landed
+// on a failed attempt is returned instead of being placed twice. Orders gated by a MarketStatusWatcher are rejected
+// before being sent.
+func (client *ApiClient) AddSpotOrderCtx(ctx context.Context, req models.AddOrderReq) (*models.GenericResponse[models.ApiOrder], error) {
+	client.mu.RLock()
+	gate := client.marketGate
+	client.mu.RUnlock()
+	if gate != nil {
+		if err := gate.CheckOrder(req); err != nil {
+			return nil, err
+		}
+	}
+
+	retryPolicy := client.retryPolicy
+	if req.ClientOrderID == "" {
+		retryPolicy = nil
+	}
+
+	for attempt := 0; ; attempt++ {
+		res, err := client.addSpotOrder(ctx, req)
+		if attempt > 0 && errors.Is(err, ErrDuplicateClientOrderID) {
+			// An earlier attempt landed after all
+			return client.GetSpotOrderByClientIDCtx(ctx, req.ClientOrderID)
+		}
+		if !retryPolicy.canRetry(attempt) || !retryPolicy.isRetryable(err) {
+			return res, err
+		}
+
+		if waitErr := retryPolicy.wait(ctx, attempt); waitErr != nil {
+			return res, err
+		}
+
+		placed, lookupErr := client.GetSpotOrderByClientIDCtx(ctx, req.ClientOrderID)
+		if lookupErr == nil {
+			return placed, nil
+		}
+		if !errors.Is(lookupErr, ErrOrderNotFound) {
+			// Whether the order landed is unknown, resubmitting could place it twice
+			return res, err
+		}
+	}
+}
+
+func (client *ApiClient) addSpotOrder(ctx context.Context, req models.AddOrderReq) (*models.GenericResponse[models.ApiOrder], error) {
 	path := models.V1SpotOrdersPath
 
-	res, err := NewHttpJsonClient[models.AddOrderReq, models.GenericResponse[models.ApiOrder]](
-		client.ApiEndpoint + path).SetHeaders(client.getHeaders("POST", path, req)).Post(req)
+	res, err := newJsonClient[models.AddOrderReq, models.GenericResponse[models.ApiOrder]](client, path).
+		WithHeaderFunc(client.signCall).PostCtx(ctx, req)
 
 	if err != nil {
 		return res, fmt.Errorf("error with http req in spot add order: %w", err)
 	}
 	if !res.Success {
-		return res, fmt.Errorf("error in spot add order %v: %v", req, res.Error)
+		return res, fmt.Errorf("error in spot add order %v: %w", req, newResponseError("POST", path, res.Error))
 	}
 
 	return res, err
 }
 
 func (client *ApiClient) GetSpotDepthBook(market models.Market) (*models.GenericResponse[models.BookSnapshot], error) {
+	return client.GetSpotDepthBookCtx(context.Background(), market)
+}
+
+func (client *ApiClient) GetSpotDepthBookCtx(ctx context.Context, market models.Market) (*models.GenericResponse[models.BookSnapshot], error) {
 	path := models.V1SpotDepthPath + "?market=" + string(market)
 
-	res, err := NewHttpJsonClient[any, models.GenericResponse[models.BookSnapshot]](
-		client.ApiEndpoint + path).SetHeaders(client.getHeaders("GET", path, nil)).Get(nil)
+	res, err := newJsonClient[any, models.GenericResponse[models.BookSnapshot]](client, path).
+		WithHeaderFunc(client.signCall).GetCtx(ctx, nil)
 
 	if err != nil {
 		return nil, fmt.Errorf("error in http req Spot get depth book: %w", err)
 	}
 	if !res.Success {
-		return res, fmt.Errorf("bad request Spot get depth book: %v", res.Error)
+		return res, fmt.Errorf("bad request Spot get depth book: %w", newResponseError("GET", path, res.Error))
 	}
 
 	return res, nil
 }
 
 func (client *ApiClient) GetSpotOrdersByMarket(market string) (*models.GenericResponse[[]models.ApiOrder], error) {
+	return client.GetSpotOrdersByMarketCtx(context.Background(), market)
+}
+
+func (client *ApiClient) GetSpotOrdersByMarketCtx(ctx context.Context, market string) (*models.GenericResponse[[]models.ApiOrder], error) {
 	path := models.V1SpotOrdersPath + "?market=" + market
 
-	res, err := NewHttpJsonClient[any, models.GenericResponse[[]models.ApiOrder]](
-		client.ApiEndpoint + path).SetHeaders(client.getHeaders("GET", path, nil)).Get(nil)
+	res, err := newJsonClient[any, models.GenericResponse[[]models.ApiOrder]](client, path).
+		WithHeaderFunc(client.signCall).GetCtx(ctx, nil)
 
 	if err != nil {
 		return nil, fmt.Errorf("error in http req spot get orders: %w", err)
 	}
 	if !res.Success {
-		return res, fmt.Errorf("bad request spot get orders: %v", res.Error)
+		return res, fmt.Errorf("bad request spot get orders: %w", newResponseError("GET", path, res.Error))
 	}
 
 	return res, nil
 }
 
 func (client *ApiClient) GetSpotOrders() (*models.GenericResponse[[]models.ApiOrder], error) {
+	return client.GetSpotOrdersCtx(context.Background())
+}
+
+func (client *ApiClient) GetSpotOrdersCtx(ctx context.Context) (*models.GenericResponse[[]models.ApiOrder], error) {
 	path := models.V1SpotOrdersPath
 
-	res, err := NewHttpJsonClient[any, models.GenericResponse[[]models.ApiOrder]](
-		client.ApiEndpoint + path).SetHeaders(client.getHeaders("GET", path, nil)).Get(nil)
+	res, err := newJsonClient[any, models.GenericResponse[[]models.ApiOrder]](client, path).
+		WithHeaderFunc(client.signCall).GetCtx(ctx, nil)
 
 	if err != nil {
 		return nil, fmt.Errorf("error in http req spot get orders: %w", err)
 	}
 	if !res.Success {
-		return res, fmt.Errorf("bad request spot get orders: %v", res.Error)
+		return res, fmt.Errorf("bad request spot get orders: %w", newResponseError("GET", path, res.Error))
 	}
 
 	return res, nil
 }
 
-func (client *ApiClient) GetSpotOrder(orderId models.OrderID) (*models.GenericResponse[models.ApiOrder], error) {
-	path := models.V1SpotOrdersPath + "/" + string(orderId)
+func (client *ApiClient) ListSpotOrders(params models.OrderParams) (*models.V1PageRes[models.ApiOrder], error) {
+	return client.ListSpotOrdersCtx(context.Background(), params)
+}
+
+// ListSpotOrdersCtx returns a page of the order history matching params. Pass PageInfo.NextCursor as the next
+// params' Cursor to get the following page.
+func (client *ApiClient) ListSpotOrdersCtx(ctx context.Context, params models.OrderParams) (*models.V1PageRes[models.ApiOrder], error) {
+	if err := params.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid spot order params: %w", err)
+	}
+
+	path := models.V1SpotOrdersPath
+	path += params.GetOrderPathParams()
+
+	res, err := newJsonClient[any, models.V1PageRes[models.ApiOrder]](client, path).
+		WithHeaderFunc(client.signCall).GetCtx(ctx, nil)
+
+	if err != nil {
+		return nil, fmt.Errorf("error in http req spot list orders: %w", err)
+	}
+
+	return res, err
+}
+
+func (client *ApiClient) ListAllSpotOrders(params models.OrderParams) ([]models.ApiOrder, error) {
+	return client.ListAllSpotOrdersCtx(context.Background(), params)
+}
+
+// maxListPages bounds how many pages ListAllSpotOrdersCtx follows, so a server that keeps returning a cursor cannot
+// loop it forever.
+const maxListPages = 1000
+
+// ListAllSpotOrdersCtx follows the cursor from params to the end of the order history and returns every order
+// matching params. It fails with the orders listed so far after maxListPages pages.
+func (client *ApiClient) ListAllSpotOrdersCtx(ctx context.Context, params models.OrderParams) ([]models.ApiOrder, error) {
+	var orders []models.ApiOrder
+	for page := 0; page < maxListPages; page++ {
+		res, err := client.ListSpotOrdersCtx(ctx, params)
+		if err != nil {
+			return orders, err
+		}
+		for _, order := range res.Result {
+			if order != nil {
+				orders = append(orders, *order)
+			}
+		}
+
+		if res.PageInfo.NextCursor == "" || len(res.Result) == 0 {
+			return orders, nil
+		}
+		params.Cursor = res.PageInfo.NextCursor
+	}
+
+	return orders, fmt.Errorf("spot orders list did not end after %d pages", maxListPages)
+}
+
+func (client *ApiClient) GetSpotOrder(order models.OrderRef) (*models.GenericResponse[models.ApiOrder], error) {
+	return client.GetSpotOrderCtx(context.Background(), order)
+}
+
+// GetSpotOrderCtx returns an order addressed by its exchange OrderID or by its ClientOrderID.
+func (client *ApiClient) GetSpotOrderCtx(ctx context.Context, order models.OrderRef) (*models.GenericResponse[models.ApiOrder], error) {
+	path := models.V1SpotOrdersPath + "/" + order.PathSegment()
 
-	res, err := NewHttpJsonClient[any, models.GenericResponse[models.ApiOrder]](
-		client.ApiEndpoint + path).SetHeaders(client.getHeaders("GET", path, nil)).Get(nil)
+	res, err := newJsonClient[any, models.GenericResponse[models.ApiOrder]](client, path).
+		WithHeaderFunc(client.signCall).GetCtx(ctx, nil)
 
 	if err != nil {
 		return nil, fmt.Errorf("error in http req spot get order: %w", err)
 	}
 	if !res.Success {
-		return res, fmt.Errorf("bad request spot get order %s: %v", orderId, res.Error)
+		return res, fmt.Errorf("bad request spot get order %s: %w", order.PathSegment(), newResponseError("GET", path, res.Error))
 	}
 
 	return res, nil
 }
 
+func (client *ApiClient) GetSpotOrderByClientID(clientOrderId models.OrderID) (*models.GenericResponse[models.ApiOrder], error) {
+	return client.GetSpotOrderByClientIDCtx(context.Background(), clientOrderId)
+}
+
+func (client *ApiClient) GetSpotOrderByClientIDCtx(ctx context.Context, clientOrderId models.OrderID) (*models.GenericResponse[models.ApiOrder], error) {
+	return client.GetSpotOrderCtx(ctx, models.ClientOrderID(clientOrderId))
+}
+
 func (client *ApiClient) CancelAllSpotOrders() error {
+	return client.CancelAllSpotOrdersCtx(context.Background())
+}
+
+func (client *ApiClient) CancelAllSpotOrdersCtx(ctx context.Context) error {
 	path := models.V1SpotOrdersPath
 
-	res, err := NewHttpJsonClient[any, models.GenericResponse[any]](
-		client.ApiEndpoint + path).SetHeaders(client.getHeaders("DELETE", path, nil)).Delete(nil)
+	res, err := newJsonClient[any, models.GenericResponse[any]](client, path).
+		WithHeaderFunc(client.signCall).DeleteCtx(ctx, nil)
 
 	if err != nil {
 		return fmt.Errorf("error in http req spot delete all orders: %w", err)
 	}
 	if !res.Success {
-		return fmt.Errorf("bad request spot delete all orders: %v", res.Error)
+		return fmt.Errorf("bad request spot delete all orders: %w", newResponseError("DELETE", path, res.Error))
 	}
 
 	return nil
 }
 
-func (client *ApiClient) CancelSpotOrder(orderId models.OrderID) (*models.GenericResponse[any], error) {
-	path := models.V1SpotOrdersPath + "/" + string(orderId)
+func (client *ApiClient) CancelAllSpotOrdersByMarket(market models.Market, side *models.BidAsk) ([]models.ApiOrder, error) {
+	return client.CancelAllSpotOrdersByMarketCtx(context.Background(), market, side)
+}
+
+// CancelAllSpotOrdersByMarketCtx cancels the open orders of market, only on side if it is not nil, and returns the
+// orders canceled. Unlike CancelAllSpotOrdersCtx it leaves the other markets alone. The cancel is scoped by the
+// server with DELETE /v1/orders?market=&side=; on servers without it the orders are listed then canceled, so orders
+// placed in between are kept.
+func (client *ApiClient) CancelAllSpotOrdersByMarketCtx(ctx context.Context, market models.Market, side *models.BidAsk) ([]models.ApiOrder, error) {
+	if !client.cancelByMarketUnsupported.Load() {
+		canceled, err := client.cancelSpotOrdersByMarket(ctx, market, side)
+		if err == nil || !isMissingCancelFilter(err) {
+			return canceled, err
+		}
+		client.cancelByMarketUnsupported.Store(true)
+	}
 
-	res, err := NewHttpJsonClient[any, models.GenericResponse[any]](
-		client.ApiEndpoint + path).SetHeaders(client.getHeaders("DELETE", path, nil)).Delete(nil)
+	open := models.Open
+	params := models.OrderParams{Status: &open, Market: market, Side: side}
+
+	return client.cancelMatchingSpotOrders(ctx, params, func(order models.ApiOrder) bool {
+		return order.Market == market && (side == nil || order.Side == *side)
+	})
+}
+
+func (client *ApiClient) cancelSpotOrdersByMarket(ctx context.Context, market models.Market, side *models.BidAsk) ([]models.ApiOrder, error) {
+	path := models.V1SpotOrdersPath + "?market=" + string(market)
+	if side != nil {
+		path += "&side=" + side.String()
+	}
+
+	res, err := newJsonClient[any, models.GenericResponse[[]models.ApiOrder]](client, path).
+		WithHeaderFunc(client.signCall).DeleteCtx(ctx, nil)
 
 	if err != nil {
-		return res, fmt.Errorf("error in http req spot delete order: %w", err)
+		return nil, fmt.Errorf("error in http req spot delete orders by market: %w", err)
 	}
 	if !res.Success {
-		return res, fmt.Errorf("bad request spot delete order %s: %v", orderId, res.Error)
+		return nil, fmt.Errorf("bad request spot delete orders by market: %w", newResponseError("DELETE", path, res.Error))
 	}
 
-	return res, nil
+	return res.Result, nil
 }
 
-func (client *ApiClient) CancelSpotOrderByClientID(clientOrderId models.OrderID) (*models.GenericResponse[any], error) {
-	path := models.V1SpotOrdersPath + "/" + models.V1SpotClientOrderIDPrefix + string(clientOrderId)
+// isMissingCancelFilter reports whether err is the server answering that it cannot cancel by market, either with no
+// such endpoint or by rejecting the market and side parameters.
+func isMissingCancelFilter(err error) bool {
+	var apiErr *APIError
+	return isMissingEndpoint(err) || (errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest)
+}
+
+func (client *ApiClient) CancelAllSpotOrdersByClientIDPrefix(prefix string) ([]models.ApiOrder, error) {
+	return client.CancelAllSpotOrdersByClientIDPrefixCtx(context.Background(), prefix)
+}
+
+// CancelAllSpotOrdersByClientIDPrefixCtx cancels the open orders whose client order ID starts with prefix, e.g. the
+// orders of one strategy sharing the key with others, and returns the orders canceled. The prefix is matched
+// client-side, see CancelAllSpotOrdersByMarketCtx.
+func (client *ApiClient) CancelAllSpotOrdersByClientIDPrefixCtx(ctx context.Context, prefix string) ([]models.ApiOrder, error) {
+	if prefix == "" {
+		return nil, fmt.Errorf("empty client order id prefix would cancel every order, use CancelAllSpotOrders")
+	}
+
+	open := models.Open
+	params := models.OrderParams{Status: &open}
+
+	return client.cancelMatchingSpotOrders(ctx, params, func(order models.ApiOrder) bool {
+		return strings.HasPrefix(string(order.ClientOrderID), prefix)
+	})
+}
+
+// cancelMatchingSpotOrders cancels the orders listed with params that match and returns those canceled. Orders that
+// were filled or gone before their cancel are skipped, other cancel failures are returned along with the orders
+// that were canceled. Canceled orders the server does not return are returned as listed with their state set to
+// Canceled, their filled size may be behind fills made before the cancel.
+func (client *ApiClient) cancelMatchingSpotOrders(ctx context.Context, params models.OrderParams, match func(models.ApiOrder) bool) ([]models.ApiOrder, error) {
+	listed, err := client.ListAllSpotOrdersCtx(ctx, params)
+	if err != nil {
+		return nil, err
+	}
 
-	res, err := NewHttpJsonClient[any, models.GenericResponse[any]](
-		client.ApiEndpoint + path).SetHeaders(client.getHeaders("DELETE", path, nil)).Delete(nil)
+	var targets []models.ApiOrder
+	var refs []models.OrderRef
+	for _, order := range listed {
+		if order.State == models.Open && match(order) {
+			targets = append(targets, order)
+			refs = append(refs, order.OrderID)
+		}
+	}
 
+	results, err := client.CancelSpotOrdersCtx(ctx, refs)
 	if err != nil {
-		return res, fmt.Errorf("error in http req spot delete order by client id: %w", err)
+		return nil, err
+	}
+
+	var canceled []models.ApiOrder
+	var errs []error
+	for i, result := range results {
+		switch {
+		case result.Err == nil && result.Order != nil:
+			canceled = append(canceled, *result.Order)
+		case result.Err == nil:
+			order := targets[i]
+			order.State = models.Canceled
+			canceled = append(canceled, order)
+		case errors.Is(result.Err, ErrOrderNotFound) || errors.Is(result.Err, ErrOrderFilled):
+		default:
+			errs = append(errs, result.Err)
+		}
+	}
+
+	return canceled, errors.Join(errs...)
+}
+
+func (client *ApiClient) CancelSpotOrder(order models.OrderRef) (*models.GenericResponse[any], error) {
+	return client.CancelSpotOrderCtx(context.Background(), order)
+}
+
+// CancelSpotOrderCtx cancels an order addressed by its exchange OrderID or by its ClientOrderID.
+func (client *ApiClient) CancelSpotOrderCtx(ctx context.Context, order models.OrderRef) (*models.GenericResponse[any], error) {
+	path := models.V1SpotOrdersPath + "/" + order.PathSegment()
+
+	res, err := newJsonClient[any, models.GenericResponse[any]](client, path).
+		WithHeaderFunc(client.signCall).DeleteCtx(ctx, nil)
+
+	if err != nil {
+		return res, fmt.Errorf("error in http req spot delete order: %w", err)
 	}
 	if !res.Success {
-		return res, fmt.Errorf("bad request spot delete order by client id %s: %v", clientOrderId, res.Error)
+		return res, fmt.Errorf("bad request spot delete order %s: %w", order.PathSegment(), newResponseError("DELETE", path, res.Error))
 	}
 
 	return res, nil
 }
 
+func (client *ApiClient) CancelSpotOrderByClientID(clientOrderId models.OrderID) (*models.GenericResponse[any], error) {
+	return client.CancelSpotOrderByClientIDCtx(context.Background(), clientOrderId)
+}
+
+func (client *ApiClient) CancelSpotOrderByClientIDCtx(ctx context.Context, clientOrderId models.OrderID) (*models.GenericResponse[any], error) {
+	return client.CancelSpotOrderCtx(ctx, models.ClientOrderID(clientOrderId))
+}
+
 func (client *ApiClient) GetSpotFills(params models.FillParams) (*models.V1PageRes[models.ApiFill], error) {
+	return client.GetSpotFillsCtx(context.Background(), params)
+}
+
+func (client *ApiClient) GetSpotFillsCtx(ctx context.Context, params models.FillParams) (*models.V1PageRes[models.ApiFill], error) {
 	path := models.V1SpotFillsPath
 	path += params.GetFillPathParams()
 
-	res, err := NewHttpJsonClient[any, models.V1PageRes[models.ApiFill]](
-		client.ApiEndpoint + path).SetHeaders(client.getHeaders("GET", path, nil)).Get(nil)
+	res, err := newJsonClient[any, models.V1PageRes[models.ApiFill]](client, path).
+		WithHeaderFunc(client.signCall).GetCtx(ctx, nil)
 
 	if err != nil {
 		return nil, fmt.Errorf("error in http req spot get fills: %w", err)
@@ -148,36 +400,32 @@ func (client *ApiClient) GetSpotFills(params models.FillParams) (*models.V1PageR
 	return res, err
 }
 
-func (client *ApiClient) GetSpotFillsByOrderID(orderID models.OrderID) (*models.GenericResponse[[]models.ApiFill], error) {
-	path := models.V1SpotOrdersPath + "/" + string(orderID) + "/fills"
+func (client *ApiClient) GetSpotFillsByOrderID(order models.OrderRef) (*models.GenericResponse[[]models.ApiFill], error) {
+	return client.GetSpotFillsByOrderIDCtx(context.Background(), order)
+}
+
+// GetSpotFillsByOrderIDCtx returns the fills of an order addressed by its exchange OrderID or by its ClientOrderID.
+func (client *ApiClient) GetSpotFillsByOrderIDCtx(ctx context.Context, order models.OrderRef) (*models.GenericResponse[[]models.ApiFill], error) {
+	path := models.V1SpotOrdersPath + "/" + order.PathSegment() + "/fills"
 
-	res, err := NewHttpJsonClient[any, models.GenericResponse[[]models.ApiFill]](
-		client.ApiEndpoint + path).SetHeaders(client.getHeaders("GET", path, nil)).Get(nil)
+	res, err := newJsonClient[any, models.GenericResponse[[]models.ApiFill]](client, path).
+		WithHeaderFunc(client.signCall).GetCtx(ctx, nil)
 
 	if err != nil {
 		return nil, fmt.Errorf("error in http req spot get fill by order ID: %w", err)
 	}
 
 	if !res.Success {
-		return res, fmt.Errorf("bad request spot fill by order id %s: %v", orderID, res.Error)
+		return res, fmt.Errorf("bad request spot fill by order id %s: %w", order.PathSegment(), newResponseError("GET", path, res.Error))
 	}
 
 	return res, err
 }
 
 func (client *ApiClient) GetSpotFillsByClientOrderID(orderID models.OrderID) (*models.GenericResponse[[]models.ApiFill], error) {
-	path := models.V1SpotOrdersPath + "/" + models.V1SpotClientOrderIDPrefix + string(orderID) + "/fills"
-
-	res, err := NewHttpJsonClient[any, models.GenericResponse[[]models.ApiFill]](
-		client.ApiEndpoint + path).SetHeaders(client.getHeaders("GET", path, nil)).Get(nil)
-
-	if err != nil {
-		return nil, fmt.Errorf("error in http req spot get fill by client order ID: %w", err)
-	}
-
-	if !res.Success {
-		return res, fmt.Errorf("bad request spot fill by client order id %s: %v", orderID, res.Error)
-	}
+	return client.GetSpotFillsByClientOrderIDCtx(context.Background(), orderID)
+}
 
-	return res, err
+func (client *ApiClient) GetSpotFillsByClientOrderIDCtx(ctx context.Context, orderID models.OrderID) (*models.GenericResponse[[]models.ApiFill], error) {
+	return client.GetSpotFillsByOrderIDCtx(ctx, models.ClientOrderID(orderID))
 }
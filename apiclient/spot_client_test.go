@@ -0,0 +1,102 @@
+package apiclient
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/Enclave-Markets/enclave-go/models"
+)
+
+func TestCancelAllSpotOrdersByMarket(t *testing.T) {
+	bid := models.Bid
+
+	t.Run("without market cancel endpoint", func(t *testing.T) {
+		exchange, server := newFakeExchange(t)
+		client := NewApiClient(server.URL)
+
+		target := exchange.add(limitOrder("AVAX-USDC", models.Bid, "a"))
+		otherSide := exchange.add(limitOrder("AVAX-USDC", models.Ask, "b"))
+		otherMarket := exchange.add(limitOrder("ETH-USDC", models.Bid, "c"))
+
+		canceled, err := client.CancelAllSpotOrdersByMarket("AVAX-USDC", &bid)
+		if err != nil {
+			t.Fatalf("cancel by market: %v", err)
+		}
+		if len(canceled) != 1 || canceled[0].OrderID != target.OrderID || canceled[0].State != models.Canceled {
+			t.Fatalf("got canceled %+v, want %s canceled", canceled, target.OrderID)
+		}
+		if state := exchange.state(target.OrderID); state != models.Canceled {
+			t.Fatalf("target is %s", state)
+		}
+		for _, order := range []models.ApiOrder{otherSide, otherMarket} {
+			if state := exchange.state(order.OrderID); state != models.Open {
+				t.Fatalf("order %s out of scope is %s", order.OrderID, state)
+			}
+		}
+
+		// The missing endpoint is remembered
+		if _, err := client.CancelAllSpotOrdersByMarket("AVAX-USDC", nil); err != nil {
+			t.Fatalf("second cancel by market: %v", err)
+		}
+		if n := exchange.received(http.MethodDelete, models.V1SpotOrdersPath+"?market=AVAX-USDC&side=buy"); n != 1 {
+			t.Fatalf("sent %d market cancels, want 1", n)
+		}
+		if n := exchange.received(http.MethodDelete, models.V1SpotOrdersPath+"?market=AVAX-USDC"); n != 0 {
+			t.Fatalf("sent %d market cancels after fallback, want 0", n)
+		}
+		if state := exchange.state(otherSide.OrderID); state != models.Canceled {
+			t.Fatalf("other side is %s after canceling both sides", state)
+		}
+	})
+
+	t.Run("with market cancel endpoint", func(t *testing.T) {
+		exchange, server := newFakeExchange(t)
+		exchange.route = func(w http.ResponseWriter, r *http.Request) bool {
+			if r.Method != http.MethodDelete || r.URL.Path != models.V1SpotOrdersPath {
+				return false
+			}
+			query := r.URL.Query()
+			if query.Get("market") != "AVAX-USDC" || query.Get("side") != "buy" {
+				http.Error(w, "unexpected query "+r.URL.RawQuery, http.StatusBadRequest)
+				return true
+			}
+			writeResult(w, []models.ApiOrder{{OrderID: "o1", Market: "AVAX-USDC", State: models.Canceled}})
+			return true
+		}
+		client := NewApiClient(server.URL)
+
+		canceled, err := client.CancelAllSpotOrdersByMarket("AVAX-USDC", &bid)
+		if err != nil {
+			t.Fatalf("cancel by market: %v", err)
+		}
+		if len(canceled) != 1 || canceled[0].OrderID != "o1" {
+			t.Fatalf("got canceled %+v, want o1", canceled)
+		}
+		if n := len(exchange.requests); n != 1 {
+			t.Fatalf("sent %d requests, want the market cancel only", n)
+		}
+	})
+}
+
+func TestCancelAllSpotOrdersByClientIDPrefix(t *testing.T) {
+	exchange, server := newFakeExchange(t)
+	client := NewApiClient(server.URL)
+
+	mine := exchange.add(limitOrder("AVAX-USDC", models.Bid, "mm-1"))
+	theirs := exchange.add(limitOrder("AVAX-USDC", models.Bid, "arb-1"))
+
+	canceled, err := client.CancelAllSpotOrdersByClientIDPrefix("mm-")
+	if err != nil {
+		t.Fatalf("cancel by prefix: %v", err)
+	}
+	if len(canceled) != 1 || canceled[0].OrderID != mine.OrderID || canceled[0].State != models.Canceled {
+		t.Fatalf("got canceled %+v, want %s canceled", canceled, mine.OrderID)
+	}
+	if state := exchange.state(theirs.OrderID); state != models.Open {
+		t.Fatalf("order out of scope is %s", state)
+	}
+
+	if _, err := client.CancelAllSpotOrdersByClientIDPrefix(""); err == nil {
+		t.Fatalf("empty prefix was accepted")
+	}
+}
@@ -0,0 +1,141 @@
+package apiclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Enclave-Markets/enclave-go/models"
+)
+
+// ClockSkew is a measurement of the local clock against the server's.
+type ClockSkew struct {
+	// Server time minus local time. Positive when the local clock is behind. Zero when the clocks agree within the
+	// precision of the measurement, half the one second resolution of the Date header plus half the round trip.
+	Offset time.Duration
+
+	// Round trip of the status call used for the measurement.
+	RTT time.Duration
+
+	MeasuredAt time.Time
+}
+
+// ClockSync estimates the offset between the local and server clocks so that ENCLAVE-TIMESTAMP is stamped with the
+// server's time. It is safe for concurrent use.
+type ClockSync struct {
+	client *ApiClient
+	onSync func(ClockSkew, error)
+
+	mu   sync.RWMutex
+	skew ClockSkew
+}
+
+// EnableClockSync measures the clock offset against the server, applies it when signing requests, and refreshes it
+// every interval until ctx is done. onSync, if not nil, is called after every refresh with the measurement or the
+// error, so skew and RTT can be alarmed on. The first measurement must succeed for the sync to be enabled.
+func (client *ApiClient) EnableClockSync(ctx context.Context, interval time.Duration, onSync func(ClockSkew, error)) (*ClockSync, error) {
+	cs := &ClockSync{
+		client: client,
+		onSync: onSync,
+	}
+
+	skew, err := cs.Sync(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	client.mu.Lock()
+	client.clock = cs
+	client.mu.Unlock()
+
+	if interval > 0 {
+		go cs.run(ctx, interval)
+	}
+
+	if onSync != nil {
+		onSync(skew, nil)
+	}
+
+	return cs, nil
+}
+
+func (cs *ClockSync) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		skew, err := cs.Sync(ctx)
+		if cs.onSync != nil && ctx.Err() == nil {
+			cs.onSync(skew, err)
+		}
+	}
+}
+
+// Sync takes a new measurement from the Date header of a status call and stores it when it succeeds.
+func (cs *ClockSync) Sync(ctx context.Context) (ClockSkew, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cs.client.ApiEndpoint+models.StatusPath, nil)
+	if err != nil {
+		return ClockSkew{}, err
+	}
+
+	httpClient := cs.client.httpClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	sent := time.Now()
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return ClockSkew{}, fmt.Errorf("error with http request to status for clock sync: %w", err)
+	}
+	received := time.Now()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	serverTime, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return ClockSkew{}, fmt.Errorf("no usable Date header for clock sync: %w", err)
+	}
+
+	// The Date header is truncated to the second, on average the server clock was half a second further. It was
+	// read about halfway through the round trip. The estimate is only good to half a second plus half the round trip,
+	// a smaller offset is no evidence the local clock is off and correcting it could only add error.
+	rtt := received.Sub(sent)
+	offset := serverTime.Add(500 * time.Millisecond).Sub(sent.Add(rtt / 2))
+	if precision := 500*time.Millisecond + rtt/2; offset <= precision && offset >= -precision {
+		offset = 0
+	}
+	skew := ClockSkew{
+		Offset:     offset,
+		RTT:        rtt,
+		MeasuredAt: received,
+	}
+
+	cs.mu.Lock()
+	cs.skew = skew
+	cs.mu.Unlock()
+
+	return skew, nil
+}
+
+// Skew returns the last successful measurement.
+func (cs *ClockSync) Skew() ClockSkew {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	return cs.skew
+}
+
+// Now returns the estimated server time.
+func (cs *ClockSync) Now() time.Time {
+	return time.Now().Add(cs.Skew().Offset)
+}
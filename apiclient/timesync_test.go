@@ -0,0 +1,42 @@
+package apiclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClockSync(t *testing.T) {
+	tests := []struct {
+		name   string
+		server time.Duration
+		want   time.Duration
+	}{
+		{"in sync", 0, 0},
+		{"local clock behind", 10 * time.Second, 10 * time.Second},
+		{"local clock ahead", -10 * time.Second, -10 * time.Second},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Date", time.Now().Add(test.server).UTC().Format(http.TimeFormat))
+			}))
+			defer server.Close()
+			client := NewApiClient(server.URL)
+
+			cs, err := client.EnableClockSync(context.Background(), 0, nil)
+			if err != nil {
+				t.Fatalf("clock sync: %v", err)
+			}
+			skew := cs.Skew()
+			// A real offset is only known to the resolution of the Date header
+			precision := 500*time.Millisecond + skew.RTT/2
+			if skew.Offset < test.want-precision || skew.Offset > test.want+precision || (test.want == 0 && skew.Offset != 0) {
+				t.Fatalf("offset %v, want %v", skew.Offset, test.want)
+			}
+		})
+	}
+}
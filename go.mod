@@ -5,6 +5,8 @@ go 1.22
 toolchain go1.22.9
 
 require (
+	github.com/gorilla/websocket v1.5.3
 	github.com/shopspring/decimal v1.4.0
+	golang.org/x/crypto v0.31.0
 	golang.org/x/time v0.5.0
 )
@@ -0,0 +1,236 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type DepositID string
+type WithdrawalID string
+type TransferID string
+
+// FundingParams filters and pages the deposit, withdrawal and transfer histories.
+type FundingParams struct {
+	StartTime *time.Time
+	EndTime   *time.Time
+	Symbol    Symbol
+	Limit     int
+	Cursor    string
+}
+
+func (fp *FundingParams) IsEmpty() bool {
+	return fp.StartTime == nil && fp.EndTime == nil && fp.Symbol == "" && fp.Limit == 0 && fp.Cursor == ""
+}
+
+func (fp *FundingParams) GetFundingPathParams() string {
+	if fp.IsEmpty() {
+		return ""
+	}
+
+	pathParams := "?"
+
+	if fp.StartTime != nil {
+		pathParams += fmt.Sprintf("startTime=%d&", fp.StartTime.UnixMilli())
+	}
+
+	if fp.EndTime != nil {
+		pathParams += fmt.Sprintf("endTime=%d&", fp.EndTime.UnixMilli())
+	}
+
+	if fp.Symbol != "" {
+		pathParams += fmt.Sprintf("symbol=%s&", fp.Symbol)
+	}
+
+	if fp.Limit > 0 {
+		pathParams += fmt.Sprintf("limit=%d&", fp.Limit)
+	}
+
+	if fp.Cursor != "" {
+		pathParams += fmt.Sprintf("cursor=%s&", fp.Cursor)
+	}
+
+	pathParams = strings.TrimSuffix(pathParams, "&")
+
+	return pathParams
+}
+
+type DepositAddress struct {
+	Symbol  Symbol `json:"symbol"`
+	Network string `json:"network"`
+	Address string `json:"address"`
+
+	// Required by some networks on top of the address.
+	Memo string `json:"memo,omitempty"`
+}
+
+type Deposit struct {
+	DepositID DepositID       `json:"id"`
+	Symbol    Symbol          `json:"symbol"`
+	Amount    decimal.Decimal `json:"amount"`
+	Network   string          `json:"network"`
+	TxHash    string          `json:"txHash"`
+	State     DepositState    `json:"status"`
+
+	CreatedAt   time.Time  `json:"createdAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// swagger:type string
+type DepositState int
+
+const (
+	// Seen on chain, waiting for confirmations
+	DepositPending DepositState = iota
+
+	// Confirmed and credited to the account
+	DepositCompleted
+
+	DepositFailed
+)
+
+func (s DepositState) String() string {
+	switch s {
+	case DepositPending:
+		return "pending"
+	case DepositCompleted:
+		return "completed"
+	case DepositFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+func (s DepositState) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`"%s"`, strings.ToLower(s.String()))), nil
+}
+
+func (s *DepositState) UnmarshalJSON(data []byte) error {
+	switch strings.ToLower(string(data)) {
+	case `"pending"`:
+		*s = DepositPending
+	case `"completed"`:
+		*s = DepositCompleted
+	case `"failed"`:
+		*s = DepositFailed
+	default:
+		return fmt.Errorf("invalid DepositState: %s", string(data))
+	}
+	return nil
+}
+
+type WithdrawalReq struct {
+	Symbol  Symbol          `json:"symbol"`
+	Amount  decimal.Decimal `json:"amount"`
+	Network string          `json:"network"`
+	Address string          `json:"address"`
+	Memo    string          `json:"memo,omitempty"`
+}
+
+type Withdrawal struct {
+	WithdrawalID WithdrawalID    `json:"id"`
+	Symbol       Symbol          `json:"symbol"`
+	Amount       decimal.Decimal `json:"amount"`
+	Fee          decimal.Decimal `json:"fee"`
+	Network      string          `json:"network"`
+	Address      string          `json:"address"`
+	Memo         string          `json:"memo,omitempty"`
+
+	// Set once the withdrawal is broadcast.
+	TxHash string `json:"txHash,omitempty"`
+
+	State WithdrawalState `json:"status"`
+
+	CreatedAt   time.Time  `json:"createdAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// swagger:type string
+type WithdrawalState int
+
+const (
+	// Requested, the funds are held until the withdrawal is processed
+	WithdrawalPending WithdrawalState = iota
+
+	// Broadcast on chain, waiting for confirmations
+	WithdrawalProcessing
+
+	WithdrawalCompleted
+
+	// Canceled before being processed, the funds are released
+	WithdrawalCanceled
+
+	// Could not be processed, the funds are released
+	WithdrawalFailed
+)
+
+func (s WithdrawalState) String() string {
+	switch s {
+	case WithdrawalPending:
+		return "pending"
+	case WithdrawalProcessing:
+		return "processing"
+	case WithdrawalCompleted:
+		return "completed"
+	case WithdrawalCanceled:
+		return "canceled"
+	case WithdrawalFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// IsFinal reports whether the withdrawal will not change state anymore.
+func (s WithdrawalState) IsFinal() bool {
+	return s == WithdrawalCompleted || s == WithdrawalCanceled || s == WithdrawalFailed
+}
+
+func (s WithdrawalState) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`"%s"`, strings.ToLower(s.String()))), nil
+}
+
+func (s *WithdrawalState) UnmarshalJSON(data []byte) error {
+	switch strings.ToLower(string(data)) {
+	case `"pending"`:
+		*s = WithdrawalPending
+	case `"processing"`:
+		*s = WithdrawalProcessing
+	case `"completed"`:
+		*s = WithdrawalCompleted
+	case `"canceled"`:
+		*s = WithdrawalCanceled
+	case `"failed"`:
+		*s = WithdrawalFailed
+	default:
+		return fmt.Errorf("invalid WithdrawalState: %s", string(data))
+	}
+	return nil
+}
+
+// AccountType is one of the account's sub-accounts that funds can be transferred between.
+type AccountType string
+
+const (
+	AccountTypeSpot   AccountType = "spot"
+	AccountTypeMargin AccountType = "margin"
+)
+
+type TransferReq struct {
+	Symbol Symbol          `json:"symbol"`
+	Amount decimal.Decimal `json:"amount"`
+	From   AccountType     `json:"from"`
+	To     AccountType     `json:"to"`
+}
+
+type Transfer struct {
+	TransferID TransferID      `json:"id"`
+	Symbol     Symbol          `json:"symbol"`
+	Amount     decimal.Decimal `json:"amount"`
+	From       AccountType     `json:"from"`
+	To         AccountType     `json:"to"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
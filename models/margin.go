@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Collateral is an asset backing the margin account.
+type Collateral struct {
+	Symbol  Symbol          `json:"symbol"`
+	Balance decimal.Decimal `json:"balance"`
+
+	// Price of the asset in quote currency.
+	Price decimal.Decimal `json:"price"`
+
+	// Fraction of the asset's value counted as collateral.
+	// example:0.9
+	Weight decimal.Decimal `json:"weight"`
+
+	// Balance * Price * Weight, in quote currency.
+	Value decimal.Decimal `json:"value"`
+}
+
+// MarginAccount is the state of the account backing perps positions. Amounts are in quote currency.
+type MarginAccount struct {
+	AccountId AccountID `json:"accountId"`
+
+	Collateral []Collateral `json:"collateral"`
+
+	// Sum of the collateral values.
+	CollateralValue decimal.Decimal `json:"collateralValue"`
+
+	// Sum of the positions' unrealized PnL.
+	UnrealizedPnL decimal.Decimal `json:"unrealizedPnl"`
+
+	// CollateralValue + UnrealizedPnL.
+	Equity decimal.Decimal `json:"equity"`
+
+	// Sums of the positions' and open orders' margins.
+	InitialMargin     decimal.Decimal `json:"initialMargin"`
+	MaintenanceMargin decimal.Decimal `json:"maintenanceMargin"`
+
+	// Equity - InitialMargin, what new orders can use.
+	FreeCollateral decimal.Decimal `json:"freeCollateral"`
+
+	// Equity / MaintenanceMargin, nil without positions. The account is liquidated when it falls below 1.
+	// example:2.5
+	HealthRatio *decimal.Decimal `json:"healthRatio,omitempty"`
+
+	Positions []Position `json:"positions"`
+
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// IsLiquidatable reports whether the account's equity no longer covers its maintenance margin.
+func (a MarginAccount) IsLiquidatable() bool {
+	return a.HealthRatio != nil && a.HealthRatio.LessThan(decimal.NewFromInt(1))
+}
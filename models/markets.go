@@ -3,6 +3,7 @@ package models
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/shopspring/decimal"
 )
@@ -68,3 +69,25 @@ func (b *BookLevel) UnmarshalJSON(data []byte) error {
 	b.Quantity = v[1]
 	return nil
 }
+
+// DepthUpdate is a depth stream message. A snapshot replaces the whole book, otherwise the levels replace the
+// levels at the same price and a level with a zero size is removed.
+type DepthUpdate struct {
+	Market   Market `json:"market"`
+	Snapshot bool   `json:"snapshot"`
+
+	// Increments by one on every update of the market, so a gap means an update was missed.
+	Sequence uint64 `json:"seq"`
+
+	Bids []BookLevel `json:"bids"`
+	Asks []BookLevel `json:"asks"`
+	Time time.Time   `json:"time"`
+}
+
+// TopOfBook is the best bid and ask of a market. A side is nil when it is empty.
+type TopOfBook struct {
+	Market Market     `json:"market"`
+	Bid    *BookLevel `json:"bid,omitempty"`
+	Ask    *BookLevel `json:"ask,omitempty"`
+	Time   time.Time  `json:"time"`
+}
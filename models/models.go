@@ -16,7 +16,31 @@ const (
 	V1SpotFillsPath  = "/v1/fills"
 	V1SpotDepthPath  = "/v1/depth"
 
+	V1SpotOrdersBatchPath = "/v1/orders/batch"
+
 	V1SpotClientOrderIDPrefix = "client:"
+
+	// Perps trading
+	V1PerpsOrdersPath             = "/v1/perps/orders"
+	V1PerpsFillsPath              = "/v1/perps/fills"
+	V1PerpsPositionsPath          = "/v1/perps/positions"
+	V1PerpsLeveragePath           = "/v1/perps/leverage"
+	V1PerpsMarginRequirementsPath = "/v1/perps/margin_requirements"
+	V1PerpsPricesPath             = "/v1/perps/prices"
+	V1PerpsFundingRatesPath       = "/v1/perps/funding_rates"
+
+	// Margin
+	V1MarginAccountPath    = "/v1/margin/account"
+	V1MarginCollateralPath = "/v1/margin/collateral"
+
+	// Funding
+	V1DepositAddressesPath = "/v1/deposits/addresses"
+	V1DepositsPath         = "/v1/deposits"
+	V1WithdrawalsPath      = "/v1/withdrawals"
+	V1TransfersPath        = "/v1/transfers"
+
+	// Streaming
+	WebsocketPath = "/ws"
 )
 
 type V1PageRes[T any] struct {
@@ -35,6 +59,29 @@ type GetPublicStatusRes struct {
 	MarketStatuses map[Market]string `json:"marketStatuses"`
 }
 
+// MarketStatus is a market's trading status, as found in GetPublicStatusRes.MarketStatuses.
+type MarketStatus string
+
+const (
+	MarketStatusActive     MarketStatus = "active"
+	MarketStatusPostOnly   MarketStatus = "postOnly"
+	MarketStatusCancelOnly MarketStatus = "cancelOnly"
+	MarketStatusHalted     MarketStatus = "halted"
+)
+
+// AcceptsOrder reports whether a new order is accepted by a market with this status. Unknown statuses accept
+// orders, the exchange has the final say.
+func (s MarketStatus) AcceptsOrder(postOnly bool) bool {
+	switch s {
+	case MarketStatusHalted, MarketStatusCancelOnly:
+		return false
+	case MarketStatusPostOnly:
+		return postOnly
+	default:
+		return true
+	}
+}
+
 type GenericResponse[T any] struct {
 	Success bool   `json:"success"`
 	Result  T      `json:"result"`
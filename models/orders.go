@@ -2,6 +2,7 @@ package models
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -10,6 +11,34 @@ import (
 
 type OrderID string
 
+// ClientOrderID is an order's client order ID, used to address the order instead of its exchange OrderID.
+type ClientOrderID OrderID
+
+// OrderRef addresses an order either by its exchange ID, an OrderID, or by its client order ID, a ClientOrderID:
+//
+//	client.GetSpotOrder(order.OrderID)
+//	client.GetSpotOrder(models.ClientOrderID(order.ClientOrderID))
+//
+// The interface is sealed: OrderID and ClientOrderID are its only implementations.
+type OrderRef interface {
+	// PathSegment is the order's segment in order paths, e.g. /v1/orders/{segment}.
+	PathSegment() string
+
+	orderRef()
+}
+
+func (id OrderID) PathSegment() string {
+	return string(id)
+}
+
+func (OrderID) orderRef() {}
+
+func (id ClientOrderID) PathSegment() string {
+	return V1SpotClientOrderIDPrefix + string(id)
+}
+
+func (ClientOrderID) orderRef() {}
+
 type AddOrderReq struct {
 	Side      BidAsk          `json:"side"`
 	Price     decimal.Decimal `json:"price"`
@@ -25,6 +54,59 @@ type AddOrderReq struct {
 	PostOnly bool `json:"postOnly,omitempty"`
 }
 
+// ReplaceOrderReq is the new price and size of a replaced order. The market, side, type and time in force are kept.
+type ReplaceOrderReq struct {
+	Price decimal.Decimal `json:"price"`
+	Size  decimal.Decimal `json:"size"`
+
+	// Client order ID of the new order. Empty derives it from the replaced order's, see NextClientOrderID.
+	ClientOrderID OrderID `json:"clientOrderId,omitempty"`
+
+	PostOnly bool `json:"postOnly,omitempty"`
+}
+
+// ReplaceOrderRes holds the replaced order in its final state and the order replacing it, nil if none was placed.
+type ReplaceOrderRes struct {
+	Old ApiOrder  `json:"old"`
+	New *ApiOrder `json:"new,omitempty"`
+}
+
+type BatchAddOrdersReq struct {
+	Orders []AddOrderReq `json:"orders"`
+}
+
+// BatchCancelOrdersReq addresses the orders to cancel by exchange ID, client ID, or both.
+type BatchCancelOrdersReq struct {
+	OrderIDs       []OrderID `json:"orderIds,omitempty"`
+	ClientOrderIDs []OrderID `json:"clientOrderIds,omitempty"`
+}
+
+// BatchOrderResult is the outcome of one item of a batch, in request order. Error is set when the item failed.
+type BatchOrderResult struct {
+	Order *ApiOrder `json:"order,omitempty"`
+	Error string    `json:"error,omitempty"`
+}
+
+// NextClientOrderID returns the client order ID of the order replacing the one with id, keeping the lineage
+// readable: "quote" is replaced by "quote-r1", then "quote-r2". An empty id stays empty.
+func NextClientOrderID(id OrderID) OrderID {
+	if id == "" {
+		return ""
+	}
+
+	i := strings.LastIndex(string(id), clientOrderIDRevisionSep)
+	if i < 0 {
+		return id + clientOrderIDRevisionSep + "1"
+	}
+	n, err := strconv.Atoi(string(id[i+len(clientOrderIDRevisionSep):]))
+	if err != nil || n < 1 {
+		return id + clientOrderIDRevisionSep + "1"
+	}
+	return OrderID(fmt.Sprintf("%s%s%d", id[:i], clientOrderIDRevisionSep, n+1))
+}
+
+const clientOrderIDRevisionSep = "-r"
+
 type BidAsk bool
 
 const Bid BidAsk = true
@@ -252,6 +334,72 @@ func (s OrderState) String() string {
 	}
 }
 
+// OrderParams filters and pages the order history. Nil and zero fields are not filtered on.
+type OrderParams struct {
+	// One of Open, FullyFilled or Canceled, see OrderStateFromQueryParam.
+	Status    *OrderState
+	Market    Market
+	Side      *BidAsk
+	StartTime *time.Time
+	EndTime   *time.Time
+	Limit     int
+	Cursor    string
+}
+
+func (op *OrderParams) IsEmpty() bool {
+	return op.Status == nil && op.Market == "" && op.Side == nil && op.StartTime == nil && op.EndTime == nil &&
+		op.Limit == 0 && op.Cursor == ""
+}
+
+// Validate checks that Status is a state the order history can be filtered on.
+func (op *OrderParams) Validate() error {
+	if op.Status == nil {
+		return nil
+	}
+	_, err := OrderStateFromQueryParam(op.Status.String())
+	return err
+}
+
+func (op *OrderParams) GetOrderPathParams() string {
+	if op.IsEmpty() {
+		return ""
+	}
+
+	pathParams := "?"
+
+	if op.Status != nil {
+		pathParams += fmt.Sprintf("status=%s&", op.Status)
+	}
+
+	if op.Market != "" {
+		pathParams += fmt.Sprintf("market=%s&", op.Market)
+	}
+
+	if op.Side != nil {
+		pathParams += fmt.Sprintf("side=%s&", op.Side)
+	}
+
+	if op.StartTime != nil {
+		pathParams += fmt.Sprintf("startTime=%d&", op.StartTime.UnixMilli())
+	}
+
+	if op.EndTime != nil {
+		pathParams += fmt.Sprintf("endTime=%d&", op.EndTime.UnixMilli())
+	}
+
+	if op.Limit > 0 {
+		pathParams += fmt.Sprintf("limit=%d&", op.Limit)
+	}
+
+	if op.Cursor != "" {
+		pathParams += fmt.Sprintf("cursor=%s&", op.Cursor)
+	}
+
+	pathParams = strings.TrimSuffix(pathParams, "&")
+
+	return pathParams
+}
+
 var ErrStatusQuery = fmt.Errorf("indicated empty order state for filter")
 
 func OrderStateFromQueryParam(s string) (OrderState, error) {
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Perps orders are placed with AddOrderReq and returned as ApiOrder, ReduceOnly only applies to them. Perps fills
+// are returned as ApiFill, with ADL set.
+
+type Position struct {
+	Market Market `json:"market"`
+
+	// Signed position size in base currency: positive when long, negative when short.
+	NetQuantity decimal.Decimal `json:"netQuantity"`
+
+	// Average price at which the position was opened.
+	AverageEntryPrice decimal.Decimal `json:"averageEntryPrice"`
+	MarkPrice         decimal.Decimal `json:"markPrice"`
+
+	// Profit or loss at the mark price if the position was closed, in quote currency.
+	UnrealizedPnL decimal.Decimal `json:"unrealizedPnl"`
+
+	// Mark price at which the position is liquidated, nil when it cannot be, e.g. a flat position.
+	LiquidationPrice *decimal.Decimal `json:"liquidationPrice,omitempty"`
+
+	// Margin needed to open the position, in quote currency.
+	InitialMargin decimal.Decimal `json:"initialMargin"`
+
+	// Margin under which the position is liquidated, in quote currency.
+	MaintenanceMargin decimal.Decimal `json:"maintenanceMargin"`
+
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// IsLong reports whether the position is long. A flat position is neither long nor short.
+func (p Position) IsLong() bool {
+	return p.NetQuantity.IsPositive()
+}
+
+func (p Position) IsShort() bool {
+	return p.NetQuantity.IsNegative()
+}
+
+type SetLeverageReq struct {
+	Market   Market          `json:"market"`
+	Leverage decimal.Decimal `json:"leverage"`
+}
+
+type Leverage struct {
+	Market   Market          `json:"market"`
+	Leverage decimal.Decimal `json:"leverage"`
+}
+
+type MarginRequirements struct {
+	Market Market `json:"market"`
+
+	// Fraction of the position's notional value required to open it.
+	// example:0.1
+	InitialMarginFraction decimal.Decimal `json:"initialMarginFraction"`
+
+	// Fraction of the position's notional value under which the position is liquidated.
+	// example:0.05
+	MaintenanceMarginFraction decimal.Decimal `json:"maintenanceMarginFraction"`
+
+	MaxLeverage decimal.Decimal `json:"maxLeverage"`
+}
+
+type PerpsPrices struct {
+	Market Market `json:"market"`
+
+	// Price used to value positions and trigger liquidations.
+	MarkPrice decimal.Decimal `json:"markPrice"`
+
+	// Spot price of the underlying, aggregated from external venues.
+	IndexPrice decimal.Decimal `json:"indexPrice"`
+
+	Time time.Time `json:"time"`
+}
+
+type FundingRate struct {
+	Market Market `json:"market"`
+
+	// Rate paid by longs to shorts for the period, shorts pay longs when negative.
+	// example:0.0001
+	Rate decimal.Decimal `json:"rate"`
+
+	// When the rate is or was applied.
+	Time time.Time `json:"time"`
+}
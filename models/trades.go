@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type TradeID string
+
+// PublicTrade is a trade printed on a market's public tape.
+type PublicTrade struct {
+	TradeID TradeID         `json:"id"`
+	Market  Market          `json:"market"`
+	Price   decimal.Decimal `json:"price"`
+	Size    decimal.Decimal `json:"size"`
+	Side    BidAsk          `json:"side"` // side of the taker
+	Time    time.Time       `json:"time"`
+}
@@ -0,0 +1,396 @@
+// Package orderbook maintains a local L2 book of a market from a depth snapshot and incremental depth updates.
+//
+//	book := orderbook.New(market, client)
+//	err := stream.SubscribeDepth(ctx, market, func(update models.DepthUpdate) {
+//		_ = book.Apply(ctx, update)
+//	})
+//	if err == nil {
+//		err = book.Sync(ctx)
+//	}
+package orderbook
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Enclave-Markets/enclave-go/models"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrNotSynced             = fmt.Errorf("order book is not synced")
+	ErrSequenceGap           = fmt.Errorf("depth sequence gap")
+	ErrCrossedBook           = fmt.Errorf("crossed book")
+	ErrInsufficientLiquidity = fmt.Errorf("not enough liquidity on the book")
+)
+
+// SnapshotFetcher fetches a depth snapshot. *apiclient.ApiClient implements it.
+type SnapshotFetcher interface {
+	GetSpotDepthBookCtx(ctx context.Context, market models.Market) (*models.GenericResponse[models.BookSnapshot], error)
+}
+
+// SnapshotRequester asks the depth stream for a new snapshot of a market, e.g. by subscribing to it again.
+// *wsclient.Client and *wsclient.Manager implement it.
+type SnapshotRequester interface {
+	RequestDepthSnapshot(ctx context.Context, market models.Market) error
+}
+
+// maxBufferedUpdates bounds the updates held while a snapshot is fetched, the oldest are dropped beyond it.
+const maxBufferedUpdates = 1000
+
+// OrderBook is a local L2 book of one market. It is safe for concurrent readers while updates are applied.
+//
+// The book is seeded either by Sync from a REST snapshot or by a snapshot from the depth stream. A REST snapshot has
+// no sequence and only holds the top of the book: the first update applied after it sets the sequence baseline, so
+// an update that was in flight while it was taken may be applied over it or missed. A stream snapshot is ordered with
+// the updates that follow it and replaces any REST seed, see WithSnapshotRequester.
+type OrderBook struct {
+	market    models.Market
+	fetcher   SnapshotFetcher
+	requester SnapshotRequester
+
+	onResync       func(reason error)
+	resyncInterval time.Duration
+
+	mu sync.RWMutex
+	// best first: bids descending, asks ascending
+	bids []models.BookLevel
+	asks []models.BookLevel
+	// last applied sequence, zero when the next update starts a new baseline
+	sequence uint64
+	synced   bool
+	// when a snapshot was last requested or fetched
+	requestedAt time.Time
+
+	// set while Sync fetches a snapshot, the updates received meanwhile are buffered and applied after it
+	syncing  bool
+	buffered []models.DepthUpdate
+	// incremented by every seed, a fetch that completes after a newer seed or fetch is discarded
+	seedGen uint64
+}
+
+type Option func(*OrderBook)
+
+// WithResyncHandler is called with the reason every time the book loses track of the market and resyncs, e.g. a
+// sequence gap or a crossed book.
+func WithResyncHandler(onResync func(reason error)) Option {
+	return func(b *OrderBook) {
+		b.onResync = onResync
+	}
+}
+
+// WithResyncInterval sets the minimum time between two resyncs while the book is not synced. Defaults to 1 second.
+func WithResyncInterval(interval time.Duration) Option {
+	return func(b *OrderBook) {
+		b.resyncInterval = interval
+	}
+}
+
+// WithSnapshotRequester makes the book resync from a depth snapshot requested from the stream rather than from a
+// REST snapshot, so it is exact again after a gap. Sync still fetches a REST snapshot.
+func WithSnapshotRequester(requester SnapshotRequester) Option {
+	return func(b *OrderBook) {
+		b.requester = requester
+	}
+}
+
+// New returns an empty book of market, synced by Sync or by the first depth snapshot applied to it.
+func New(market models.Market, fetcher SnapshotFetcher, opts ...Option) *OrderBook {
+	b := &OrderBook{
+		market:         market,
+		fetcher:        fetcher,
+		resyncInterval: time.Second,
+		// the caller syncs the book, or the depth subscription starts with a snapshot
+		requestedAt: time.Now(),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+func (b *OrderBook) Market() models.Market {
+	return b.market
+}
+
+// Sync seeds the book from a REST depth snapshot. Depth updates applied while the snapshot is fetched are buffered
+// and applied over it once it is loaded, the first one setting the sequence baseline. A gap among them is returned
+// and leaves the book not synced. A stream snapshot applied meanwhile wins over the fetched one.
+func (b *OrderBook) Sync(ctx context.Context) error {
+	b.mu.Lock()
+	b.synced = false
+	b.syncing = true
+	b.buffered = nil
+	b.seedGen++
+	gen := b.seedGen
+	b.requestedAt = time.Now()
+	b.mu.Unlock()
+
+	res, err := b.fetcher.GetSpotDepthBookCtx(ctx, b.market)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if gen != b.seedGen {
+		// seeded by a stream snapshot or a newer Sync meanwhile
+		return nil
+	}
+	buffered := b.buffered
+	b.syncing = false
+	b.buffered = nil
+	if err != nil {
+		return fmt.Errorf("error syncing order book %s: %w", b.market, err)
+	}
+
+	b.loadSnapshot(res.Result.Bids, res.Result.Asks)
+	b.sequence = 0
+	b.synced = true
+	for _, update := range buffered {
+		if _, reason := b.applyUpdate(update); reason != nil {
+			return reason
+		}
+	}
+	return nil
+}
+
+// Apply applies a depth update. On a sequence gap or if the update leaves the book crossed, the book resyncs and the
+// reason is returned along with any resync error. Updates are buffered while Sync fetches a snapshot. Other
+// incremental updates received while the book is not synced are dropped with ErrNotSynced, and the book resyncs at
+// most once per resync interval.
+func (b *OrderBook) Apply(ctx context.Context, update models.DepthUpdate) error {
+	if update.Market != "" && update.Market != b.market {
+		return fmt.Errorf("depth update for %s applied to %s book", update.Market, b.market)
+	}
+
+	resync, reason := b.apply(update)
+	if !resync {
+		return reason
+	}
+
+	if b.onResync != nil && reason != ErrNotSynced {
+		b.onResync(reason)
+	}
+	if err := b.resync(ctx); err != nil {
+		return fmt.Errorf("%w, resync failed: %w", reason, err)
+	}
+	return reason
+}
+
+// resync requests a stream snapshot when the book has a requester and fetches a REST snapshot otherwise.
+func (b *OrderBook) resync(ctx context.Context) error {
+	if b.requester != nil {
+		return b.requester.RequestDepthSnapshot(ctx, b.market)
+	}
+	return b.Sync(ctx)
+}
+
+// apply updates the book and returns whether it must resync and why the book is not synced, if it is not.
+func (b *OrderBook) apply(update models.DepthUpdate) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if update.Snapshot {
+		b.loadSnapshot(update.Bids, update.Asks)
+		b.sequence = update.Sequence
+		b.synced = true
+		b.syncing = false
+		b.buffered = nil
+		b.seedGen++
+		return false, nil
+	}
+
+	if b.syncing {
+		if len(b.buffered) == maxBufferedUpdates {
+			b.buffered = b.buffered[1:]
+		}
+		b.buffered = append(b.buffered, update)
+		return false, nil
+	}
+	if !b.synced {
+		if time.Since(b.requestedAt) < b.resyncInterval {
+			return false, ErrNotSynced
+		}
+		b.requestedAt = time.Now()
+		return true, ErrNotSynced
+	}
+	return b.applyUpdate(update)
+}
+
+// applyUpdate applies an incremental update to the synced book, see apply.
+func (b *OrderBook) applyUpdate(update models.DepthUpdate) (bool, error) {
+	if b.sequence != 0 && update.Sequence <= b.sequence {
+		// already applied
+		return false, nil
+	}
+	if b.sequence != 0 && update.Sequence != b.sequence+1 {
+		b.desync()
+		return true, fmt.Errorf("%w on %s: expected %d, got %d", ErrSequenceGap, b.market, b.sequence+1, update.Sequence)
+	}
+
+	for _, level := range update.Bids {
+		b.bids = setLevel(b.bids, level, models.Bid)
+	}
+	for _, level := range update.Asks {
+		b.asks = setLevel(b.asks, level, models.Ask)
+	}
+	b.sequence = update.Sequence
+
+	if len(b.bids) > 0 && len(b.asks) > 0 && b.bids[0].Price.GreaterThanOrEqual(b.asks[0].Price) {
+		b.desync()
+		return true, fmt.Errorf("%w on %s: bid %s >= ask %s", ErrCrossedBook, b.market, b.bids[0].Price, b.asks[0].Price)
+	}
+	return false, nil
+}
+
+// desync marks the book as not synced by a resync started now.
+func (b *OrderBook) desync() {
+	b.synced = false
+	b.requestedAt = time.Now()
+}
+
+func (b *OrderBook) loadSnapshot(bids, asks []models.BookLevel) {
+	b.bids = b.bids[:0]
+	for _, level := range bids {
+		b.bids = setLevel(b.bids, level, models.Bid)
+	}
+	b.asks = b.asks[:0]
+	for _, level := range asks {
+		b.asks = setLevel(b.asks, level, models.Ask)
+	}
+}
+
+// better reports whether price a is ahead of price b on side.
+func better(side models.BidAsk, a, b decimal.Decimal) bool {
+	if side == models.Bid {
+		return a.GreaterThan(b)
+	}
+	return a.LessThan(b)
+}
+
+// setLevel replaces the level at level.Price in the sorted levels of side, removing it when its size is zero.
+func setLevel(levels []models.BookLevel, level models.BookLevel, side models.BidAsk) []models.BookLevel {
+	i := sort.Search(len(levels), func(i int) bool {
+		return !better(side, levels[i].Price, level.Price)
+	})
+	exists := i < len(levels) && levels[i].Price.Equal(level.Price)
+
+	switch {
+	case level.Quantity.IsZero() && exists:
+		return append(levels[:i], levels[i+1:]...)
+	case level.Quantity.IsZero():
+		return levels
+	case exists:
+		levels[i] = level
+		return levels
+	default:
+		levels = append(levels, models.BookLevel{})
+		copy(levels[i+1:], levels[i:])
+		levels[i] = level
+		return levels
+	}
+}
+
+// Synced reports whether the book reflects the market, i.e. it was seeded by a snapshot and no gap was detected
+// since.
+func (b *OrderBook) Synced() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.synced
+}
+
+// Sequence returns the sequence of the last applied update or snapshot.
+func (b *OrderBook) Sequence() uint64 {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return b.sequence
+}
+
+func (b *OrderBook) BestBid() (models.BookLevel, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if len(b.bids) == 0 {
+		return models.BookLevel{}, false
+	}
+	return b.bids[0], true
+}
+
+func (b *OrderBook) BestAsk() (models.BookLevel, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if len(b.asks) == 0 {
+		return models.BookLevel{}, false
+	}
+	return b.asks[0], true
+}
+
+// Depth returns the best n levels of each side, or every level when n is not positive.
+func (b *OrderBook) Depth(n int) models.BookSnapshot {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return models.BookSnapshot{
+		Bids: topLevels(b.bids, n),
+		Asks: topLevels(b.asks, n),
+	}
+}
+
+func topLevels(levels []models.BookLevel, n int) []models.BookLevel {
+	if n <= 0 || n > len(levels) {
+		n = len(levels)
+	}
+	top := make([]models.BookLevel, n)
+	copy(top, levels[:n])
+	return top
+}
+
+// CumulativeSize returns the size resting on side of the book at price or better.
+func (b *OrderBook) CumulativeSize(side models.BidAsk, price decimal.Decimal) decimal.Decimal {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	total := decimal.Zero
+	for _, level := range b.levels(side) {
+		if better(side, price, level.Price) {
+			break
+		}
+		total = total.Add(level.Quantity)
+	}
+	return total
+}
+
+// CostToFill returns the quote cost of taking size on side: a Bid buys from the asks and an Ask sells into the
+// bids. It returns ErrInsufficientLiquidity if the book cannot fill the whole size.
+func (b *OrderBook) CostToFill(side models.BidAsk, size decimal.Decimal) (decimal.Decimal, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	remaining := size
+	cost := decimal.Zero
+	for _, level := range b.levels(side.Opposite()) {
+		if !remaining.IsPositive() {
+			break
+		}
+		take := decimal.Min(remaining, level.Quantity)
+		cost = cost.Add(take.Mul(level.Price))
+		remaining = remaining.Sub(take)
+	}
+
+	if remaining.IsPositive() {
+		return cost, fmt.Errorf("%w on %s: %s of %s left", ErrInsufficientLiquidity, b.market, remaining, size)
+	}
+	return cost, nil
+}
+
+func (b *OrderBook) levels(side models.BidAsk) []models.BookLevel {
+	if side == models.Bid {
+		return b.bids
+	}
+	return b.asks
+}
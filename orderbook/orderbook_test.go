@@ -0,0 +1,279 @@
+package orderbook
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Enclave-Markets/enclave-go/models"
+	"github.com/shopspring/decimal"
+)
+
+const market models.Market = "AVAX-USDC"
+
+// fakeFetcher returns snapshot from GetSpotDepthBookCtx. While block is set, the fetch waits for it to be closed.
+type fakeFetcher struct {
+	mu       sync.Mutex
+	snapshot models.BookSnapshot
+	block    chan struct{}
+	fetches  int
+}
+
+func (f *fakeFetcher) GetSpotDepthBookCtx(ctx context.Context, market models.Market) (*models.GenericResponse[models.BookSnapshot], error) {
+	f.mu.Lock()
+	f.fetches++
+	block := f.block
+	snapshot := f.snapshot
+	f.mu.Unlock()
+
+	if block != nil {
+		<-block
+	}
+	return &models.GenericResponse[models.BookSnapshot]{Success: true, Result: snapshot}, nil
+}
+
+type fakeRequester struct {
+	requests int
+}
+
+func (r *fakeRequester) RequestDepthSnapshot(ctx context.Context, market models.Market) error {
+	r.requests++
+	return nil
+}
+
+func level(price, size int64) models.BookLevel {
+	return models.BookLevel{Price: decimal.NewFromInt(price), Quantity: decimal.NewFromInt(size)}
+}
+
+func update(sequence uint64, bids, asks []models.BookLevel) models.DepthUpdate {
+	return models.DepthUpdate{Market: market, Sequence: sequence, Bids: bids, Asks: asks}
+}
+
+func snapshot(sequence uint64, bids, asks []models.BookLevel) models.DepthUpdate {
+	u := update(sequence, bids, asks)
+	u.Snapshot = true
+	return u
+}
+
+func TestSetLevel(t *testing.T) {
+	tests := []struct {
+		name   string
+		side   models.BidAsk
+		levels []models.BookLevel
+		set    models.BookLevel
+		want   []models.BookLevel
+	}{
+		{"insert into empty", models.Bid, nil, level(10, 1), []models.BookLevel{level(10, 1)}},
+		{"insert best bid", models.Bid, []models.BookLevel{level(10, 1)}, level(11, 2),
+			[]models.BookLevel{level(11, 2), level(10, 1)}},
+		{"insert worst bid", models.Bid, []models.BookLevel{level(10, 1)}, level(9, 2),
+			[]models.BookLevel{level(10, 1), level(9, 2)}},
+		{"insert best ask", models.Ask, []models.BookLevel{level(10, 1)}, level(9, 2),
+			[]models.BookLevel{level(9, 2), level(10, 1)}},
+		{"insert between asks", models.Ask, []models.BookLevel{level(10, 1), level(12, 1)}, level(11, 2),
+			[]models.BookLevel{level(10, 1), level(11, 2), level(12, 1)}},
+		{"replace", models.Bid, []models.BookLevel{level(11, 1), level(10, 1)}, level(10, 5),
+			[]models.BookLevel{level(11, 1), level(10, 5)}},
+		{"remove", models.Ask, []models.BookLevel{level(10, 1), level(11, 1)}, level(10, 0),
+			[]models.BookLevel{level(11, 1)}},
+		{"remove missing", models.Ask, []models.BookLevel{level(10, 1)}, level(12, 0),
+			[]models.BookLevel{level(10, 1)}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := setLevel(append([]models.BookLevel(nil), test.levels...), test.set, test.side)
+			if !equalLevels(got, test.want) {
+				t.Fatalf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func equalLevels(a, b []models.BookLevel) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Price.Equal(b[i].Price) || !a[i].Quantity.Equal(b[i].Quantity) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestApply(t *testing.T) {
+	requester := &fakeRequester{}
+	var reasons []error
+	book := New(market, &fakeFetcher{},
+		WithSnapshotRequester(requester),
+		WithResyncHandler(func(reason error) { reasons = append(reasons, reason) }),
+		WithResyncInterval(time.Hour),
+	)
+	ctx := context.Background()
+
+	if err := book.Apply(ctx, update(1, []models.BookLevel{level(9, 1)}, nil)); !errors.Is(err, ErrNotSynced) {
+		t.Fatalf("update before any snapshot: got %v, want ErrNotSynced", err)
+	}
+
+	steps := []struct {
+		name    string
+		update  models.DepthUpdate
+		wantErr error
+		bid     int64
+		ask     int64
+	}{
+		{"snapshot", snapshot(10, []models.BookLevel{level(9, 1), level(8, 1)}, []models.BookLevel{level(11, 1)}), nil, 9, 11},
+		{"already applied", update(10, []models.BookLevel{level(9, 0)}, nil), nil, 9, 11},
+		{"next", update(11, []models.BookLevel{level(10, 2)}, nil), nil, 10, 11},
+		{"level removed", update(12, nil, []models.BookLevel{level(11, 0), level(12, 3)}), nil, 10, 12},
+		{"gap", update(14, []models.BookLevel{level(11, 1)}, nil), ErrSequenceGap, 10, 12},
+		{"dropped while not synced", update(15, nil, nil), ErrNotSynced, 10, 12},
+		{"new snapshot", snapshot(20, []models.BookLevel{level(9, 1)}, []models.BookLevel{level(10, 1)}), nil, 9, 10},
+		{"crossed", update(21, []models.BookLevel{level(10, 1)}, nil), ErrCrossedBook, 10, 10},
+	}
+	for _, step := range steps {
+		err := book.Apply(ctx, step.update)
+		if !errors.Is(err, step.wantErr) || (step.wantErr == nil && err != nil) {
+			t.Fatalf("%s: got %v, want %v", step.name, err, step.wantErr)
+		}
+		bid, _ := book.BestBid()
+		ask, _ := book.BestAsk()
+		if !bid.Price.Equal(decimal.NewFromInt(step.bid)) || !ask.Price.Equal(decimal.NewFromInt(step.ask)) {
+			t.Fatalf("%s: top of book %s/%s, want %d/%d", step.name, bid.Price, ask.Price, step.bid, step.ask)
+		}
+	}
+
+	if book.Synced() {
+		t.Fatalf("book synced after a crossed update")
+	}
+	// The gap and the crossed book, throttled updates while not synced are not resyncs
+	if requester.requests != 2 || len(reasons) != 2 {
+		t.Fatalf("got %d snapshot requests and %d resync reasons, want 2", requester.requests, len(reasons))
+	}
+}
+
+func TestApplyThrottlesResync(t *testing.T) {
+	requester := &fakeRequester{}
+	book := New(market, &fakeFetcher{}, WithSnapshotRequester(requester), WithResyncInterval(50*time.Millisecond))
+	ctx := context.Background()
+
+	for i := 0; i < 10; i++ {
+		_ = book.Apply(ctx, update(uint64(i+1), nil, nil))
+	}
+	if requester.requests != 0 {
+		t.Fatalf("got %d snapshot requests within the interval, want 0", requester.requests)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	for i := 0; i < 10; i++ {
+		_ = book.Apply(ctx, update(uint64(i+11), nil, nil))
+	}
+	if requester.requests != 1 {
+		t.Fatalf("got %d snapshot requests after the interval, want 1", requester.requests)
+	}
+}
+
+// TestSyncBuffersUpdates checks that the updates received while the REST snapshot is fetched are applied over it,
+// the first one setting the sequence baseline.
+func TestSyncBuffersUpdates(t *testing.T) {
+	fetcher := &fakeFetcher{
+		snapshot: models.BookSnapshot{
+			Bids: []models.BookLevel{level(9, 1)},
+			Asks: []models.BookLevel{level(11, 1)},
+		},
+		block: make(chan struct{}),
+	}
+	book := New(market, fetcher)
+	ctx := context.Background()
+
+	synced := make(chan error, 1)
+	go func() { synced <- book.Sync(ctx) }()
+	for {
+		fetcher.mu.Lock()
+		fetches := fetcher.fetches
+		fetcher.mu.Unlock()
+		if fetches > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := book.Apply(ctx, update(50, []models.BookLevel{level(10, 1)}, nil)); err != nil {
+		t.Fatalf("buffered update: %v", err)
+	}
+	if err := book.Apply(ctx, update(51, nil, []models.BookLevel{level(11, 0), level(12, 1)})); err != nil {
+		t.Fatalf("buffered update: %v", err)
+	}
+	if book.Synced() {
+		t.Fatalf("book synced before the snapshot was fetched")
+	}
+
+	close(fetcher.block)
+	if err := <-synced; err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	bid, _ := book.BestBid()
+	ask, _ := book.BestAsk()
+	if !book.Synced() || book.Sequence() != 51 || !bid.Price.Equal(decimal.NewFromInt(10)) ||
+		!ask.Price.Equal(decimal.NewFromInt(12)) {
+		t.Fatalf("synced=%v sequence=%d top=%s/%s, want synced at 51 with 10/12",
+			book.Synced(), book.Sequence(), bid.Price, ask.Price)
+	}
+
+	if err := book.Apply(ctx, update(53, nil, nil)); !errors.Is(err, ErrSequenceGap) {
+		t.Fatalf("gap after the baseline: got %v, want ErrSequenceGap", err)
+	}
+}
+
+func TestSyncResyncsFromRest(t *testing.T) {
+	fetcher := &fakeFetcher{snapshot: models.BookSnapshot{Bids: []models.BookLevel{level(9, 1)}}}
+	book := New(market, fetcher)
+	ctx := context.Background()
+
+	if err := book.Sync(ctx); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if err := book.Apply(ctx, update(5, nil, nil)); err != nil {
+		t.Fatalf("baseline update: %v", err)
+	}
+	if err := book.Apply(ctx, update(7, nil, nil)); !errors.Is(err, ErrSequenceGap) {
+		t.Fatalf("gap: got %v, want ErrSequenceGap", err)
+	}
+	if fetcher.fetches != 2 || !book.Synced() || book.Sequence() != 0 {
+		t.Fatalf("fetches=%d synced=%v sequence=%d, want resynced from a second fetch",
+			fetcher.fetches, book.Synced(), book.Sequence())
+	}
+}
+
+func TestQueries(t *testing.T) {
+	book := New(market, &fakeFetcher{})
+	_ = book.Apply(context.Background(), snapshot(1,
+		[]models.BookLevel{level(10, 1), level(9, 2), level(8, 3)},
+		[]models.BookLevel{level(11, 1), level(12, 2), level(13, 3)},
+	))
+
+	depth := book.Depth(2)
+	if !equalLevels(depth.Bids, []models.BookLevel{level(10, 1), level(9, 2)}) ||
+		!equalLevels(depth.Asks, []models.BookLevel{level(11, 1), level(12, 2)}) {
+		t.Fatalf("depth %+v", depth)
+	}
+	if size := book.CumulativeSize(models.Bid, decimal.NewFromInt(9)); !size.Equal(decimal.NewFromInt(3)) {
+		t.Fatalf("cumulative bid size to 9: %s, want 3", size)
+	}
+	if size := book.CumulativeSize(models.Ask, decimal.NewFromInt(12)); !size.Equal(decimal.NewFromInt(3)) {
+		t.Fatalf("cumulative ask size to 12: %s, want 3", size)
+	}
+
+	// Buying 2 takes 1 at 11 and 1 at 12
+	cost, err := book.CostToFill(models.Bid, decimal.NewFromInt(2))
+	if err != nil || !cost.Equal(decimal.NewFromInt(23)) {
+		t.Fatalf("cost to buy 2: %s, %v, want 23", cost, err)
+	}
+	if _, err := book.CostToFill(models.Ask, decimal.NewFromInt(7)); !errors.Is(err, ErrInsufficientLiquidity) {
+		t.Fatalf("selling more than the bids: got %v, want ErrInsufficientLiquidity", err)
+	}
+}
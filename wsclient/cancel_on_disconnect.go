@@ -0,0 +1,244 @@
+package wsclient
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ChannelCancelOnDisconnect is the private channel that makes the exchange cancel the account's open orders when
+// its heartbeats stop, with models.CancelAfterTimeout as the cancel reason.
+const ChannelCancelOnDisconnect = "cancelOnDisconnect"
+
+type cancelOnDisconnectArgs struct {
+	TimeoutMs int64 `json:"timeoutMs,omitempty"`
+}
+
+type heartbeatAck struct {
+	Time time.Time `json:"time"`
+}
+
+type HeartbeatEventType int
+
+const (
+	// A heartbeat was not acknowledged before the next one was due, or could not be sent.
+	HeartbeatMissed HeartbeatEventType = iota
+
+	// A heartbeat was acknowledged after one or more were missed.
+	HeartbeatRecovered
+
+	// The session stopped, either closed or because the connection was lost. Once the connection is lost the
+	// exchange cancels the open orders after its timeout.
+	CancelOnDisconnectEnded
+)
+
+func (t HeartbeatEventType) String() string {
+	switch t {
+	case HeartbeatMissed:
+		return "heartbeatMissed"
+	case HeartbeatRecovered:
+		return "heartbeatRecovered"
+	case CancelOnDisconnectEnded:
+		return "cancelOnDisconnectEnded"
+	default:
+		return "unknown"
+	}
+}
+
+type HeartbeatEvent struct {
+	Type HeartbeatEventType
+
+	// Consecutive heartbeats missed so far.
+	Missed int
+
+	// Why a heartbeat could not be sent, or why the session ended when the connection was lost.
+	Err error
+
+	Time time.Time
+}
+
+type CancelOnDisconnectConfig struct {
+	// How often heartbeats are sent. Defaults to 1 second.
+	HeartbeatInterval time.Duration
+
+	// How long the exchange waits without heartbeat before canceling. Zero keeps the exchange default.
+	Timeout time.Duration
+
+	// Called from the session goroutine for every HeartbeatEvent.
+	OnEvent func(HeartbeatEvent)
+}
+
+// CancelOnDisconnectSession keeps cancel-on-disconnect protection alive with heartbeats. If the process dies or the
+// connection drops, the exchange pulls the account's resting orders.
+//
+// A session lives and dies with its Client: it is not re-established on a new connection. Use
+// Manager.StartCancelOnDisconnect to keep the protection on across reconnects.
+type CancelOnDisconnectSession struct {
+	client  *Client
+	config  CancelOnDisconnectConfig
+	acks    chan struct{}
+	stop    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+}
+
+// StartCancelOnDisconnect subscribes to cancel-on-disconnect and starts sending heartbeats. It requires Login.
+func (c *Client) StartCancelOnDisconnect(ctx context.Context, config CancelOnDisconnectConfig) (*CancelOnDisconnectSession, error) {
+	if config.HeartbeatInterval <= 0 {
+		config.HeartbeatInterval = time.Second
+	}
+
+	s := &CancelOnDisconnectSession{
+		client:  c,
+		config:  config,
+		acks:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	handle(c, ChannelCancelOnDisconnect, "", func(heartbeatAck) {
+		select {
+		case s.acks <- struct{}{}:
+		default:
+		}
+	})
+	err := c.send(ctx, request{
+		Op:      "subscribe",
+		Channel: ChannelCancelOnDisconnect,
+		Args:    cancelOnDisconnectArgs{TimeoutMs: config.Timeout.Milliseconds()},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	go s.run()
+	return s, nil
+}
+
+func (s *CancelOnDisconnectSession) run() {
+	defer close(s.stopped)
+
+	ticker := time.NewTicker(s.config.HeartbeatInterval)
+	defer ticker.Stop()
+
+	missed := 0
+	awaitingAck := s.heartbeat(&missed)
+	for {
+		select {
+		case <-s.stop:
+			s.emit(HeartbeatEvent{Type: CancelOnDisconnectEnded, Missed: missed})
+			return
+		case <-s.client.Done():
+			err := s.client.Err()
+			if err == nil {
+				err = ErrClosed
+			}
+			s.emit(HeartbeatEvent{Type: CancelOnDisconnectEnded, Missed: missed, Err: err})
+			return
+		case <-s.acks:
+			awaitingAck = false
+			if missed > 0 {
+				missed = 0
+				s.emit(HeartbeatEvent{Type: HeartbeatRecovered})
+			}
+		case <-ticker.C:
+			if awaitingAck {
+				missed++
+				s.emit(HeartbeatEvent{Type: HeartbeatMissed, Missed: missed})
+			}
+			awaitingAck = s.heartbeat(&missed)
+		}
+	}
+}
+
+// heartbeat sends a heartbeat and reports whether an ack is now expected.
+func (s *CancelOnDisconnectSession) heartbeat(missed *int) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.HeartbeatInterval)
+	defer cancel()
+
+	if err := s.client.send(ctx, request{Op: "heartbeat", Channel: ChannelCancelOnDisconnect}); err != nil {
+		*missed++
+		s.emit(HeartbeatEvent{Type: HeartbeatMissed, Missed: *missed, Err: err})
+		return false
+	}
+	return true
+}
+
+func (s *CancelOnDisconnectSession) emit(event HeartbeatEvent) {
+	if s.config.OnEvent != nil {
+		event.Time = time.Now()
+		s.config.OnEvent(event)
+	}
+}
+
+// Close stops the heartbeats and unsubscribes, turning the protection off without canceling orders. If the
+// unsubscribe cannot be sent, the exchange cancels the orders once its timeout elapses.
+func (s *CancelOnDisconnectSession) Close(ctx context.Context) error {
+	s.once.Do(func() {
+		close(s.stop)
+	})
+	<-s.stopped
+
+	return s.client.Unsubscribe(ctx, ChannelCancelOnDisconnect, "")
+}
+
+// ManagedCancelOnDisconnect is cancel-on-disconnect protection kept on by a Manager: a CancelOnDisconnectSession is
+// started on every connection, after the login and along with the replayed subscriptions.
+type ManagedCancelOnDisconnect struct {
+	config CancelOnDisconnectConfig
+
+	mu      sync.Mutex
+	session *CancelOnDisconnectSession
+	closed  bool
+}
+
+// StartCancelOnDisconnect turns cancel-on-disconnect on for the life of the manager, or until Close. It requires
+// ManagerConfig.Auth.
+//
+// Every lost connection ends the session of that connection, reported as a CancelOnDisconnectEnded event, and the
+// exchange cancels the account's orders once its timeout elapses without heartbeats. Heartbeats resume on the next
+// connection, reconnecting within the timeout keeps the orders.
+func (m *Manager) StartCancelOnDisconnect(ctx context.Context, config CancelOnDisconnectConfig) (*ManagedCancelOnDisconnect, error) {
+	if m.config.Auth == nil {
+		return nil, fmt.Errorf("cancel-on-disconnect requires ManagerConfig.Auth")
+	}
+
+	managed := &ManagedCancelOnDisconnect{config: config}
+	err := m.addSubscription(ctx, managed.start)
+	if err != nil {
+		return nil, err
+	}
+	return managed, nil
+}
+
+// start starts a session on a new connection.
+func (s *ManagedCancelOnDisconnect) start(ctx context.Context, c *Client) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	session, err := c.StartCancelOnDisconnect(ctx, s.config)
+	if err != nil {
+		return err
+	}
+	s.session = session
+	return nil
+}
+
+// Close turns the protection off on the live connection, see CancelOnDisconnectSession.Close, and stops it from being
+// started on later ones.
+func (s *ManagedCancelOnDisconnect) Close(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	session := s.session
+	s.session = nil
+	s.mu.Unlock()
+
+	if session == nil {
+		return nil
+	}
+	return session.Close(ctx)
+}
@@ -0,0 +1,142 @@
+package wsclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+type fakeAuth struct{}
+
+func (fakeAuth) AuthHeadersCtx(ctx context.Context, httpVerb string, path string, request any) (map[string]string, error) {
+	return map[string]string{"ENCLAVE-KEY-ID": "key", "ENCLAVE-TIMESTAMP": "1", "ENCLAVE-SIGN": "sign"}, nil
+}
+
+// fakeStream accepts logins, subscriptions and heartbeats, recording the requests of every connection. The first
+// connection is dropped after its first heartbeat.
+type fakeStream struct {
+	mu    sync.Mutex
+	conns [][]string
+}
+
+func (f *fakeStream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	f.mu.Lock()
+	f.conns = append(f.conns, nil)
+	index := len(f.conns) - 1
+	f.mu.Unlock()
+
+	for {
+		var req request
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		f.mu.Lock()
+		f.conns[index] = append(f.conns[index], strings.TrimSpace(req.Op+" "+req.Channel))
+		f.mu.Unlock()
+
+		var reply message
+		switch req.Op {
+		case "login":
+			reply = message{Type: "loggedIn"}
+		case "subscribe", "unsubscribe":
+			reply = message{Type: req.Op + "d", Channel: req.Channel}
+		case "heartbeat":
+			if index == 0 {
+				return
+			}
+			data, _ := json.Marshal(heartbeatAck{Time: time.Now()})
+			reply = message{Type: "update", Channel: ChannelCancelOnDisconnect, Data: data}
+		default:
+			continue
+		}
+		if err := conn.WriteJSON(reply); err != nil {
+			return
+		}
+	}
+}
+
+// requests returns the requests received on connection index, waiting for it to receive want.
+func (f *fakeStream) requests(t *testing.T, index int, want string) []string {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		f.mu.Lock()
+		var requests []string
+		if index < len(f.conns) {
+			requests = append(requests, f.conns[index]...)
+		}
+		f.mu.Unlock()
+		for _, request := range requests {
+			if request == want {
+				return requests
+			}
+		}
+	}
+	t.Fatalf("connection %d did not receive %q", index, want)
+	return nil
+}
+
+func TestManagerCancelOnDisconnect(t *testing.T) {
+	stream := &fakeStream{}
+	server := httptest.NewServer(stream)
+	defer server.Close()
+
+	var mu sync.Mutex
+	var events []HeartbeatEventType
+	manager := NewManager("ws"+strings.TrimPrefix(server.URL, "http"), ManagerConfig{
+		Auth:           fakeAuth{},
+		InitialBackoff: time.Millisecond,
+	}, WithPingInterval(0))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	protection, err := manager.StartCancelOnDisconnect(ctx, CancelOnDisconnectConfig{
+		HeartbeatInterval: 10 * time.Millisecond,
+		OnEvent: func(event HeartbeatEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			events = append(events, event.Type)
+		},
+	})
+	if err != nil {
+		t.Fatalf("start cancel-on-disconnect: %v", err)
+	}
+	go func() { _ = manager.Run(ctx) }()
+
+	// The first connection is dropped, the protection is started again on the next one
+	stream.requests(t, 0, "heartbeat cancelOnDisconnect")
+	requests := stream.requests(t, 1, "heartbeat cancelOnDisconnect")
+	if requests[0] != "login" || requests[1] != "subscribe cancelOnDisconnect" {
+		t.Fatalf("second connection received %v, want a login then the subscription", requests)
+	}
+
+	if err := protection.Close(ctx); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	stream.requests(t, 1, "unsubscribe cancelOnDisconnect")
+
+	mu.Lock()
+	defer mu.Unlock()
+	ended := 0
+	for _, event := range events {
+		if event == CancelOnDisconnectEnded {
+			ended++
+		}
+	}
+	if ended != 2 {
+		t.Fatalf("got events %v, want the end of both sessions", events)
+	}
+}
@@ -0,0 +1,423 @@
+package wsclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Enclave-Markets/enclave-go/models"
+	"github.com/gorilla/websocket"
+)
+
+// Public channels.
+const (
+	ChannelDepth     = "depth"
+	ChannelTopOfBook = "topOfBook"
+	ChannelTrades    = "trades"
+)
+
+// request is a message sent to the stream.
+type request struct {
+	Op      string          `json:"op"`
+	Channel string          `json:"channel,omitempty"`
+	Markets []models.Market `json:"markets,omitempty"`
+	Args    any             `json:"args,omitempty"`
+}
+
+// message is a message received from the stream.
+type message struct {
+	Type    string          `json:"type"`
+	Channel string          `json:"channel,omitempty"`
+	Market  models.Market   `json:"market,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// StreamError is an error reported by the server on the stream, e.g. a rejected subscription.
+type StreamError struct {
+	Channel string
+	Message string
+}
+
+func (e *StreamError) Error() string {
+	if e.Channel == "" {
+		return "stream error: " + e.Message
+	}
+	return fmt.Sprintf("stream error on %s: %s", e.Channel, e.Message)
+}
+
+var ErrClosed = fmt.Errorf("stream closed")
+
+type subscription struct {
+	channel string
+	market  models.Market
+}
+
+// Client is a connection to Enclave's websocket stream. Handlers are called from the connection's read loop, one
+// message at a time and in the order received, so a slow handler delays every subscription. Client is safe for
+// concurrent use.
+type Client struct {
+	conn *websocket.Conn
+
+	dialer       *websocket.Dialer
+	onError      func(error)
+	pingInterval time.Duration
+	pongTimeout  time.Duration
+
+	writeMu sync.Mutex
+
+	mu          sync.RWMutex
+	handlers    map[subscription]func(json.RawMessage) error
+	loginResult chan error
+	closing     bool
+
+	done chan struct{}
+	err  error
+}
+
+type Option func(*Client)
+
+// WithErrorHandler receives errors that are not tied to a call, e.g. StreamError messages and undecodable updates.
+func WithErrorHandler(onError func(error)) Option {
+	return func(c *Client) {
+		c.onError = onError
+	}
+}
+
+// WithPingInterval sets how often a ping is sent to keep the connection alive. Zero disables pings.
+func WithPingInterval(interval time.Duration) Option {
+	return func(c *Client) {
+		c.pingInterval = interval
+	}
+}
+
+// WithPongTimeout sets how long the connection may go without receiving anything, pongs included, before it is
+// considered lost and closed. Defaults to twice the ping interval, without pings no timeout applies by default.
+func WithPongTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.pongTimeout = timeout
+	}
+}
+
+// WithDialer sets the websocket dialer, e.g. for proxies or custom TLS roots.
+func WithDialer(dialer *websocket.Dialer) Option {
+	return func(c *Client) {
+		c.dialer = dialer
+	}
+}
+
+// EndpointFromEnv returns the stream url of the "sandbox" or "prod" environment.
+func EndpointFromEnv(env string) (string, error) {
+	switch strings.ToLower(env) {
+	case "sandbox":
+		return "wss://api-sandbox.enclave.market" + models.WebsocketPath, nil
+	case "prod":
+		return "wss://api.enclave.market" + models.WebsocketPath, nil
+	default:
+		return "", fmt.Errorf("unknown env: %s", env)
+	}
+}
+
+// EndpointFromApi returns the stream url served next to a REST api endpoint such as ApiClient.ApiEndpoint.
+func EndpointFromApi(apiEndpoint string) string {
+	endpoint := strings.TrimSuffix(apiEndpoint, "/")
+	if rest, ok := strings.CutPrefix(endpoint, "https://"); ok {
+		endpoint = "wss://" + rest
+	} else if rest, ok := strings.CutPrefix(endpoint, "http://"); ok {
+		endpoint = "ws://" + rest
+	}
+	return endpoint + models.WebsocketPath
+}
+
+// Dial connects to the stream at url.
+func Dial(ctx context.Context, url string, opts ...Option) (*Client, error) {
+	c := &Client{
+		dialer:       websocket.DefaultDialer,
+		pingInterval: 15 * time.Second,
+		handlers:     map[subscription]func(json.RawMessage) error{},
+		done:         make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.pongTimeout == 0 {
+		c.pongTimeout = 2 * c.pingInterval
+	}
+
+	conn, _, err := c.dialer.DialContext(ctx, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error dialing stream %s: %w", url, err)
+	}
+	c.conn = conn
+
+	// Control frames are handled inside reads, they keep the connection alive like any message
+	conn.SetPongHandler(func(string) error {
+		c.extendReadDeadline()
+		return nil
+	})
+	conn.SetPingHandler(func(data string) error {
+		c.extendReadDeadline()
+		err := conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+		if errors.Is(err, websocket.ErrCloseSent) {
+			return nil
+		}
+		return err
+	})
+
+	go c.readLoop()
+	if c.pingInterval > 0 {
+		go c.pingLoop()
+	}
+
+	return c, nil
+}
+
+// DialEnv connects to the stream of the "sandbox" or "prod" environment.
+func DialEnv(ctx context.Context, env string, opts ...Option) (*Client, error) {
+	url, err := EndpointFromEnv(env)
+	if err != nil {
+		return nil, err
+	}
+	return Dial(ctx, url, opts...)
+}
+
+// SubscribeDepth streams depth updates of market, starting with a snapshot.
+func (c *Client) SubscribeDepth(ctx context.Context, market models.Market, handler func(models.DepthUpdate)) error {
+	return subscribe(ctx, c, ChannelDepth, market, handler)
+}
+
+// SubscribeTopOfBook streams the best bid and ask of market.
+func (c *Client) SubscribeTopOfBook(ctx context.Context, market models.Market, handler func(models.TopOfBook)) error {
+	return subscribe(ctx, c, ChannelTopOfBook, market, handler)
+}
+
+// SubscribeTrades streams the public trades of market.
+func (c *Client) SubscribeTrades(ctx context.Context, market models.Market, handler func(models.PublicTrade)) error {
+	return subscribe(ctx, c, ChannelTrades, market, handler)
+}
+
+// RequestDepthSnapshot subscribes to the depth of market again so that the stream sends a new snapshot, e.g. to
+// resync an order book. The depth handler is kept.
+func (c *Client) RequestDepthSnapshot(ctx context.Context, market models.Market) error {
+	if err := c.send(ctx, request{Op: "unsubscribe", Channel: ChannelDepth, Markets: marketList(market)}); err != nil {
+		return err
+	}
+	return c.send(ctx, request{Op: "subscribe", Channel: ChannelDepth, Markets: marketList(market)})
+}
+
+// Unsubscribe stops a subscription. The handler is removed even if the request cannot be sent.
+func (c *Client) Unsubscribe(ctx context.Context, channel string, market models.Market) error {
+	c.mu.Lock()
+	delete(c.handlers, subscription{channel: channel, market: market})
+	c.mu.Unlock()
+
+	return c.send(ctx, request{Op: "unsubscribe", Channel: channel, Markets: marketList(market)})
+}
+
+// ToChan returns a handler delivering messages on ch. The read loop blocks while ch is full, so ch must be drained
+// for other subscriptions to make progress.
+func ToChan[T any](ch chan<- T) func(T) {
+	return func(v T) {
+		ch <- v
+	}
+}
+
+// subscribe registers handler for the channel and market and asks the server for the subscription. An empty market
+// subscribes to every market of the channel.
+func subscribe[T any](ctx context.Context, c *Client, channel string, market models.Market, handler func(T)) error {
+	handle(c, channel, market, handler)
+	return c.send(ctx, request{Op: "subscribe", Channel: channel, Markets: marketList(market)})
+}
+
+// handle registers handler for the updates of the channel and market.
+func handle[T any](c *Client, channel string, market models.Market, handler func(T)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.handlers[subscription{channel: channel, market: market}] = func(data json.RawMessage) error {
+		var v T
+		if err := json.Unmarshal(data, &v); err != nil {
+			return fmt.Errorf("failed to decode %s update: %w", channel, err)
+		}
+		handler(v)
+		return nil
+	}
+}
+
+func marketList(market models.Market) []models.Market {
+	if market == "" {
+		return nil
+	}
+	return []models.Market{market}
+}
+
+// send writes req, giving up at the context deadline.
+func (c *Client) send(ctx context.Context, req request) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-c.done:
+		return ErrClosed
+	default:
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	deadline, _ := ctx.Deadline()
+	if err := c.conn.SetWriteDeadline(deadline); err != nil {
+		return err
+	}
+	if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		return fmt.Errorf("error writing %s request: %w", req.Op, err)
+	}
+	return nil
+}
+
+// readLoop dispatches messages until the connection fails. A connection silent for longer than the pong timeout,
+// e.g. half-open, is closed so that Done fires.
+func (c *Client) readLoop() {
+	defer close(c.done)
+
+	for {
+		c.extendReadDeadline()
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				err = fmt.Errorf("nothing received for %s: %w", c.pongTimeout, err)
+			}
+
+			c.mu.Lock()
+			closing := c.closing
+			if !closing {
+				c.err = err
+			}
+			c.mu.Unlock()
+			if !closing {
+				_ = c.conn.Close()
+			}
+			return
+		}
+
+		var msg message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.reportError(fmt.Errorf("failed to decode stream message: %w", err))
+			continue
+		}
+		c.dispatch(msg)
+	}
+}
+
+// extendReadDeadline gives the connection another pong timeout to receive something.
+func (c *Client) extendReadDeadline() {
+	if c.pongTimeout > 0 {
+		_ = c.conn.SetReadDeadline(time.Now().Add(c.pongTimeout))
+	}
+}
+
+func (c *Client) dispatch(msg message) {
+	switch msg.Type {
+	case "loggedIn":
+		c.completeLogin(nil)
+		return
+	case "error":
+		streamErr := &StreamError{Channel: msg.Channel, Message: msg.Error}
+		if msg.Channel == "login" {
+			c.completeLogin(streamErr)
+		} else {
+			c.reportError(streamErr)
+		}
+		return
+	case "subscribed", "unsubscribed", "pong":
+		return
+	}
+
+	c.mu.RLock()
+	handler, ok := c.handlers[subscription{channel: msg.Channel, market: msg.Market}]
+	if !ok {
+		handler, ok = c.handlers[subscription{channel: msg.Channel}]
+	}
+	c.mu.RUnlock()
+
+	if ok {
+		if err := handler(msg.Data); err != nil {
+			c.reportError(err)
+		}
+	}
+}
+
+// completeLogin hands the outcome of a login to the Login call waiting for it.
+func (c *Client) completeLogin(err error) {
+	c.mu.Lock()
+	result := c.loginResult
+	c.loginResult = nil
+	c.mu.Unlock()
+
+	if result != nil {
+		result <- err
+	}
+}
+
+func (c *Client) reportError(err error) {
+	if c.onError != nil {
+		c.onError(err)
+	}
+}
+
+func (c *Client) pingLoop() {
+	ticker := time.NewTicker(c.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), c.pingInterval)
+		err := c.send(ctx, request{Op: "ping"})
+		cancel()
+		if err != nil && !errors.Is(err, ErrClosed) {
+			c.reportError(err)
+		}
+	}
+}
+
+// Done is closed when the connection is lost or closed.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns the error that ended the connection, or nil while it is open or after Close.
+func (c *Client) Err() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.err
+}
+
+// Close closes the connection and waits for the read loop to stop.
+func (c *Client) Close() error {
+	c.mu.Lock()
+	c.closing = true
+	c.mu.Unlock()
+
+	c.writeMu.Lock()
+	_ = c.conn.SetWriteDeadline(time.Now().Add(time.Second))
+	_ = c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	c.writeMu.Unlock()
+
+	err := c.conn.Close()
+	<-c.done
+	return err
+}
@@ -0,0 +1,486 @@
+package wsclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/Enclave-Markets/enclave-go/models"
+	"github.com/shopspring/decimal"
+)
+
+// Reconciler fetches the account state missed while disconnected. *apiclient.ApiClient implements it.
+type Reconciler interface {
+	GetSpotOrderCtx(ctx context.Context, order models.OrderRef) (*models.GenericResponse[models.ApiOrder], error)
+	ListSpotOrdersCtx(ctx context.Context, params models.OrderParams) (*models.V1PageRes[models.ApiOrder], error)
+	GetSpotFillsCtx(ctx context.Context, params models.FillParams) (*models.V1PageRes[models.ApiFill], error)
+}
+
+type LifecycleEventType int
+
+const (
+	// The connection is up, logged in and every subscription was replayed.
+	Connected LifecycleEventType = iota
+
+	// The connection was lost. The manager reconnects after a backoff.
+	Disconnected
+
+	// After a reconnect, the orders and fills missed while disconnected were fetched over REST and delivered to
+	// the account handler.
+	Resynced
+)
+
+func (t LifecycleEventType) String() string {
+	switch t {
+	case Connected:
+		return "connected"
+	case Disconnected:
+		return "disconnected"
+	case Resynced:
+		return "resynced"
+	default:
+		return "unknown"
+	}
+}
+
+type LifecycleEvent struct {
+	Type LifecycleEventType
+
+	// Connection attempts made since the last successful connection.
+	Attempt int
+
+	// Why the connection was lost or an attempt failed, or why the resync failed.
+	Err error
+
+	Time time.Time
+}
+
+type ManagerConfig struct {
+	// Logs in every connection when set. Required for SubscribeAccount.
+	Auth Authenticator
+
+	// Fetches missed orders and fills after a reconnect when set.
+	Reconciler Reconciler
+
+	// Backoff between connection attempts. It doubles after every failed attempt up to MaxBackoff. Defaults to
+	// 500ms and 30s.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// Called from the manager goroutine for every LifecycleEvent.
+	OnLifecycle func(LifecycleEvent)
+}
+
+// Manager keeps a stream connection alive: it reconnects with exponential backoff, logs in again, replays every
+// subscription and reconciles the account over REST so that no order or fill event is silently lost. Subscriptions
+// last for the life of the manager.
+type Manager struct {
+	url    string
+	config ManagerConfig
+	opts   []Option
+
+	mu      sync.Mutex
+	client  *Client
+	replays []func(ctx context.Context, c *Client) error
+	account *accountTracker
+}
+
+func NewManager(url string, config ManagerConfig, opts ...Option) *Manager {
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = 500 * time.Millisecond
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = 30 * time.Second
+	}
+
+	return &Manager{
+		url:    url,
+		config: config,
+		opts:   opts,
+	}
+}
+
+// stableConnection is how long a connection must stay up for the backoff to start over. A server that accepts
+// connections and drops them right away is retried with a growing backoff like one refusing them.
+const stableConnection = time.Minute
+
+// Run connects and keeps the connection alive until ctx is done, then closes it and returns the context error.
+func (m *Manager) Run(ctx context.Context) error {
+	backoff := m.config.InitialBackoff
+	attempt := 0
+	var disconnectedAt time.Time
+
+	for {
+		attempt++
+		client, err := m.connect(ctx)
+		if err != nil {
+			m.emit(LifecycleEvent{Type: Disconnected, Attempt: attempt, Err: err})
+		} else {
+			connectedAt := time.Now()
+			m.emit(LifecycleEvent{Type: Connected, Attempt: attempt})
+			if !disconnectedAt.IsZero() && m.config.Reconciler != nil {
+				m.emit(LifecycleEvent{Type: Resynced, Err: m.reconcile(ctx, disconnectedAt)})
+			}
+			attempt = 0
+
+			select {
+			case <-ctx.Done():
+				client.Close()
+				return ctx.Err()
+			case <-client.Done():
+			}
+			disconnectedAt = time.Now()
+
+			err = client.Err()
+			if err == nil {
+				err = ErrClosed
+			}
+			m.emit(LifecycleEvent{Type: Disconnected, Err: err})
+			if time.Since(connectedAt) >= stableConnection {
+				backoff = m.config.InitialBackoff
+			}
+		}
+
+		if err := sleep(ctx, jitter(backoff)); err != nil {
+			return err
+		}
+		backoff = min(backoff*2, m.config.MaxBackoff)
+	}
+}
+
+// connect dials, logs in and replays the subscriptions.
+func (m *Manager) connect(ctx context.Context) (*Client, error) {
+	client, err := Dial(ctx, m.url, m.opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.config.Auth != nil {
+		if err := client.Login(ctx, m.config.Auth); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("stream login failed: %w", err)
+		}
+	}
+
+	// Holding the lock while replaying keeps subscriptions added concurrently from being replayed twice or missed.
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, replay := range m.replays {
+		if err := replay(ctx, client); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("failed to replay subscription: %w", err)
+		}
+	}
+	m.client = client
+	return client, nil
+}
+
+// addSubscription records subscribe for replay and applies it to the live connection, if any.
+func (m *Manager) addSubscription(ctx context.Context, subscribe func(ctx context.Context, c *Client) error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.replays = append(m.replays, subscribe)
+	if m.client == nil {
+		return nil
+	}
+
+	select {
+	case <-m.client.Done():
+		// replayed on reconnect
+		return nil
+	default:
+		return subscribe(ctx, m.client)
+	}
+}
+
+func (m *Manager) SubscribeDepth(ctx context.Context, market models.Market, handler func(models.DepthUpdate)) error {
+	return m.addSubscription(ctx, func(ctx context.Context, c *Client) error {
+		return c.SubscribeDepth(ctx, market, handler)
+	})
+}
+
+// RequestDepthSnapshot asks the live connection for a new depth snapshot of market, see Client.RequestDepthSnapshot.
+// Without a live connection it does nothing, the snapshot comes with the replayed subscription.
+func (m *Manager) RequestDepthSnapshot(ctx context.Context, market models.Market) error {
+	m.mu.Lock()
+	client := m.client
+	m.mu.Unlock()
+
+	if client == nil {
+		return nil
+	}
+	if err := client.RequestDepthSnapshot(ctx, market); err != nil && !errors.Is(err, ErrClosed) {
+		return err
+	}
+	return nil
+}
+
+func (m *Manager) SubscribeTopOfBook(ctx context.Context, market models.Market, handler func(models.TopOfBook)) error {
+	return m.addSubscription(ctx, func(ctx context.Context, c *Client) error {
+		return c.SubscribeTopOfBook(ctx, market, handler)
+	})
+}
+
+func (m *Manager) SubscribeTrades(ctx context.Context, market models.Market, handler func(models.PublicTrade)) error {
+	return m.addSubscription(ctx, func(ctx context.Context, c *Client) error {
+		return c.SubscribeTrades(ctx, market, handler)
+	})
+}
+
+// SubscribeAccount streams the account's order and fill events. After a reconnect, orders whose state changed and
+// fills that happened while disconnected are delivered to handler from the REST reconciliation, each event at most
+// once. Only one account subscription is supported per manager.
+func (m *Manager) SubscribeAccount(ctx context.Context, handler func(AccountEvent)) error {
+	if m.config.Auth == nil {
+		return fmt.Errorf("account subscription requires ManagerConfig.Auth")
+	}
+
+	m.mu.Lock()
+	if m.account != nil {
+		m.mu.Unlock()
+		return fmt.Errorf("account is already subscribed")
+	}
+	tracker := newAccountTracker(handler)
+	m.account = tracker
+	m.mu.Unlock()
+
+	return m.addSubscription(ctx, func(ctx context.Context, c *Client) error {
+		return c.SubscribeAccount(ctx, tracker.deliver)
+	})
+}
+
+// reconcile fetches the orders and fills missed since the connection was lost at disconnectedAt and hands the new
+// ones to the account handler. Orders are fetched like a Poller does: the open ones, the ones created since the
+// disconnect, and the ones the tracker still has live, which ended while disconnected.
+//
+// Fills are fetched from a time rather than from a cursor: the stream carries no cursor, so the one of the last
+// reconciliation would replay every fill since then, most of them delivered from the stream and long forgotten by
+// the tracker. Starting one dedup window before the last fill seen covers what was missed, and the fill ids of that
+// window are still known.
+func (m *Manager) reconcile(ctx context.Context, disconnectedAt time.Time) error {
+	m.mu.Lock()
+	tracker := m.account
+	m.mu.Unlock()
+	if tracker == nil {
+		return nil
+	}
+
+	since := disconnectedAt.Add(-dedupWindow)
+	_, err := syncOrders(ctx, m.config.Reconciler, tracker, []models.Market{""}, &since, func(order models.ApiOrder) {
+		tracker.deliverSince(OrderEvent{Order: order}, since)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reconcile orders: %w", err)
+	}
+
+	// Page from the last fill seen, following the cursor to the end
+	params := models.FillParams{}
+	fillsSince := tracker.fillsSince()
+	params.StartTime = &fillsSince
+	for page := 0; page < maxReconcilePages; page++ {
+		fills, err := m.config.Reconciler.GetSpotFillsCtx(ctx, params)
+		if err != nil {
+			return fmt.Errorf("failed to reconcile fills: %w", err)
+		}
+		for _, fill := range fills.Result {
+			tracker.deliver(FillEvent{Fill: *fill})
+		}
+		if fills.PageInfo.NextCursor == "" || len(fills.Result) == 0 {
+			break
+		}
+		params.Cursor = fills.PageInfo.NextCursor
+	}
+
+	return nil
+}
+
+const maxReconcilePages = 100
+
+func (m *Manager) emit(event LifecycleEvent) {
+	if m.config.OnLifecycle != nil {
+		event.Time = time.Now()
+		m.config.OnLifecycle(event)
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// jitter picks uniformly in [d/2, d] so clients dropped together do not reconnect together.
+func jitter(d time.Duration) time.Duration {
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+type orderMark struct {
+	state  models.OrderState
+	filled decimal.Decimal
+
+	// when the order was filled, canceled or rejected, zero while it is live
+	endedAt time.Time
+}
+
+// after reports whether the order moved forward from prev.
+func (m orderMark) after(prev orderMark) bool {
+	if !prev.endedAt.IsZero() || m.filled.LessThan(prev.filled) || orderStage(m.state) < orderStage(prev.state) {
+		return false
+	}
+	return m.state != prev.state || m.filled.GreaterThan(prev.filled)
+}
+
+// orderStage orders the states an order goes through: New, then live, then ended.
+func orderStage(state models.OrderState) int {
+	switch state {
+	case models.New:
+		return 0
+	case models.FullyFilled, models.Canceled, models.Rejected:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// dedupWindow is how long ended orders and fills are remembered to drop the ones delivered twice, and how far
+// before the last seen fill the reconciliation starts.
+const dedupWindow = time.Minute
+
+// accountTracker remembers what was delivered to the account handler, so events seen both on the stream and in the
+// reconciliation are delivered once and orders never appear to go back, e.g. from Canceled to Open when a REST
+// snapshot older than the stream is reconciled. Live orders are remembered until they end, ended orders and fills
+// for dedupWindow. The handler is called one event at a time, like a Client's handlers.
+type accountTracker struct {
+	handler func(AccountEvent)
+	// held while the handler runs, the stream and the reconciliation deliver from different goroutines
+	deliverMu sync.Mutex
+
+	mu       sync.Mutex
+	orders   map[models.OrderID]orderMark
+	fills    map[models.FillID]time.Time
+	lastFill time.Time
+}
+
+func newAccountTracker(handler func(AccountEvent)) *accountTracker {
+	return &accountTracker{
+		handler:  handler,
+		orders:   map[models.OrderID]orderMark{},
+		fills:    map[models.FillID]time.Time{},
+		lastFill: time.Now(),
+	}
+}
+
+func (t *accountTracker) deliver(event AccountEvent) {
+	t.deliverSince(event, time.Time{})
+}
+
+// deliverSince delivers event if it is new. Unknown orders that ended before since are not: either they were
+// delivered and forgotten, or they ended before the account was tracked.
+func (t *accountTracker) deliverSince(event AccountEvent, since time.Time) {
+	t.deliverMu.Lock()
+	defer t.deliverMu.Unlock()
+
+	if t.observe(event, since) {
+		t.handler(event)
+	}
+}
+
+// observe records the event and reports whether it is new, see deliverSince. An order event is new when it moves the
+// order forward: a later state or a larger filled size, and nothing after the order ended.
+func (t *accountTracker) observe(event AccountEvent, since time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch e := event.(type) {
+	case OrderEvent:
+		mark := orderMark{state: e.Order.State, filled: e.Order.FilledQuantity, endedAt: orderEndedAt(e.Order)}
+		prev, ok := t.orders[e.Order.OrderID]
+		if ok && !mark.after(prev) {
+			return false
+		}
+		if !ok && !mark.endedAt.IsZero() && mark.endedAt.Before(since) {
+			return false
+		}
+		t.orders[e.Order.OrderID] = mark
+
+		// Forgetting only orders that ended a window ago keeps them older than the since of any later reconciliation
+		cutoff := time.Now().Add(-dedupWindow)
+		for id, mark := range t.orders {
+			if !mark.endedAt.IsZero() && mark.endedAt.Before(cutoff) {
+				delete(t.orders, id)
+			}
+		}
+		return true
+	case FillEvent:
+		if _, ok := t.fills[e.Fill.FillID]; ok {
+			return false
+		}
+		t.fills[e.Fill.FillID] = e.Fill.CreatedAt
+		if e.Fill.CreatedAt.After(t.lastFill) {
+			t.lastFill = e.Fill.CreatedAt
+		}
+		for id, at := range t.fills {
+			if at.Before(t.lastFill.Add(-dedupWindow)) {
+				delete(t.fills, id)
+			}
+		}
+		return true
+	default:
+		return true
+	}
+}
+
+// liveOrders returns the orders seen that did not end yet.
+func (t *accountTracker) liveOrders() []models.OrderID {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var orderIds []models.OrderID
+	for id, mark := range t.orders {
+		if mark.endedAt.IsZero() {
+			orderIds = append(orderIds, id)
+		}
+	}
+	return orderIds
+}
+
+// fillsSince returns where the fill reconciliation starts.
+func (t *accountTracker) fillsSince() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.lastFill.Add(-dedupWindow)
+}
+
+// orderEndedAt returns when order was filled, canceled or rejected, or zero while it is live.
+func orderEndedAt(order models.ApiOrder) time.Time {
+	var at *time.Time
+	switch order.State {
+	case models.FullyFilled:
+		at = order.FilledAt
+	case models.Canceled:
+		at = order.CanceledAt
+	case models.Rejected:
+	default:
+		return time.Time{}
+	}
+
+	if at != nil && !at.IsZero() {
+		return *at
+	}
+	if !order.CreatedAt.IsZero() {
+		return order.CreatedAt
+	}
+	return time.Now()
+}
@@ -0,0 +1,247 @@
+package wsclient
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Enclave-Markets/enclave-go/models"
+	"github.com/shopspring/decimal"
+)
+
+// fakeSource serves orders and fills from memory. It implements OrderSource and Reconciler.
+type fakeSource struct {
+	mu     sync.Mutex
+	orders map[models.OrderID]models.ApiOrder
+	fills  []models.ApiFill
+
+	// Fills per page, zero returns every fill in one page.
+	fillPage int
+
+	// Every ListSpotOrdersCtx and GetSpotFillsCtx call, in order.
+	orderParams []models.OrderParams
+	fillParams  []models.FillParams
+}
+
+func newFakeSource() *fakeSource {
+	return &fakeSource{orders: map[models.OrderID]models.ApiOrder{}}
+}
+
+func (s *fakeSource) set(order models.ApiOrder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.orders[order.OrderID] = order
+}
+
+func (s *fakeSource) GetSpotOrderCtx(ctx context.Context, order models.OrderRef) (*models.GenericResponse[models.ApiOrder], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &models.GenericResponse[models.ApiOrder]{Success: true, Result: s.orders[models.OrderID(order.PathSegment())]}, nil
+}
+
+// ListSpotOrdersCtx lists the orders matching the status and created since the start time, in one page.
+func (s *fakeSource) ListSpotOrdersCtx(ctx context.Context, params models.OrderParams) (*models.V1PageRes[models.ApiOrder], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.orderParams = append(s.orderParams, params)
+	res := &models.V1PageRes[models.ApiOrder]{}
+	for _, order := range s.orders {
+		if params.Status != nil && order.State != *params.Status {
+			continue
+		}
+		if params.StartTime != nil && order.CreatedAt.Before(*params.StartTime) {
+			continue
+		}
+		listed := order
+		res.Result = append(res.Result, &listed)
+	}
+	return res, nil
+}
+
+func (s *fakeSource) GetSpotFillsCtx(ctx context.Context, params models.FillParams) (*models.V1PageRes[models.ApiFill], error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fillParams = append(s.fillParams, params)
+	var matching []models.ApiFill
+	for _, fill := range s.fills {
+		if params.StartTime == nil || !fill.CreatedAt.Before(*params.StartTime) {
+			matching = append(matching, fill)
+		}
+	}
+
+	// The cursor is the index of the first fill of the page
+	start, _ := strconv.Atoi(params.Cursor)
+	end := len(matching)
+	res := &models.V1PageRes[models.ApiFill]{}
+	if s.fillPage > 0 && start+s.fillPage < end {
+		end = start + s.fillPage
+		res.PageInfo.NextCursor = strconv.Itoa(end)
+	}
+	for _, fill := range matching[min(start, end):end] {
+		listed := fill
+		res.Result = append(res.Result, &listed)
+	}
+	return res, nil
+}
+
+func testOrder(id models.OrderID, state models.OrderState, filled int64) models.ApiOrder {
+	now := time.Now()
+	order := models.ApiOrder{
+		OrderID:        id,
+		State:          state,
+		FilledQuantity: decimal.NewFromInt(filled),
+		CreatedAt:      now,
+	}
+	switch state {
+	case models.FullyFilled:
+		order.FilledAt = &now
+	case models.Canceled:
+		order.CanceledAt = &now
+	}
+	return order
+}
+
+func TestAccountTrackerOrders(t *testing.T) {
+	steps := []struct {
+		name  string
+		order models.ApiOrder
+		want  bool
+	}{
+		{"placed", testOrder("o1", models.Open, 0), true},
+		{"same state again", testOrder("o1", models.Open, 0), false},
+		{"partially filled", testOrder("o1", models.Open, 2), true},
+		{"older snapshot", testOrder("o1", models.Open, 1), false},
+		{"back to new", testOrder("o1", models.New, 2), false},
+		{"canceled", testOrder("o1", models.Canceled, 2), true},
+		{"open after canceled", testOrder("o1", models.Open, 2), false},
+		{"filled after canceled", testOrder("o1", models.FullyFilled, 3), false},
+		{"other order", testOrder("o2", models.New, 0), true},
+		{"other order open", testOrder("o2", models.Open, 0), true},
+	}
+
+	tracker := newAccountTracker(func(AccountEvent) {})
+	for _, step := range steps {
+		if got := tracker.observe(OrderEvent{Order: step.order}, time.Time{}); got != step.want {
+			t.Fatalf("%s: observe returned %v, want %v", step.name, got, step.want)
+		}
+	}
+
+	live := tracker.liveOrders()
+	if len(live) != 1 || live[0] != "o2" {
+		t.Fatalf("live orders %v, want [o2]", live)
+	}
+}
+
+func TestAccountTrackerEndedBeforeSince(t *testing.T) {
+	tracker := newAccountTracker(func(AccountEvent) {})
+
+	ended := testOrder("o1", models.Canceled, 0)
+	if tracker.observe(OrderEvent{Order: ended}, time.Now().Add(time.Second)) {
+		t.Fatalf("unknown order ended before since was delivered")
+	}
+	if !tracker.observe(OrderEvent{Order: ended}, time.Now().Add(-time.Second)) {
+		t.Fatalf("unknown order ended after since was dropped")
+	}
+}
+
+func TestAccountTrackerFills(t *testing.T) {
+	tracker := newAccountTracker(func(AccountEvent) {})
+	now := time.Now()
+
+	fill := models.ApiFill{FillID: "f1", CreatedAt: now}
+	if !tracker.observe(FillEvent{Fill: fill}, time.Time{}) {
+		t.Fatalf("new fill was dropped")
+	}
+	if tracker.observe(FillEvent{Fill: fill}, time.Time{}) {
+		t.Fatalf("fill was delivered twice")
+	}
+	if since := tracker.fillsSince(); !since.Equal(now.Add(-dedupWindow)) {
+		t.Fatalf("fills since %v, want a window before the last fill", since)
+	}
+
+	// Fills a window older than the last one are forgotten
+	late := models.ApiFill{FillID: "f2", CreatedAt: now.Add(2 * dedupWindow)}
+	tracker.observe(FillEvent{Fill: late}, time.Time{})
+	if !tracker.observe(FillEvent{Fill: fill}, time.Time{}) {
+		t.Fatalf("fill older than the window is still remembered")
+	}
+}
+
+func TestAccountTrackerSerializesDelivery(t *testing.T) {
+	var running, overlaps atomic.Int32
+	tracker := newAccountTracker(func(AccountEvent) {
+		if running.Add(1) > 1 {
+			overlaps.Add(1)
+		}
+		time.Sleep(time.Millisecond)
+		running.Add(-1)
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				id := models.FillID(fmt.Sprintf("f%d-%d", i, j))
+				tracker.deliver(FillEvent{Fill: models.ApiFill{FillID: id, CreatedAt: time.Now()}})
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if n := overlaps.Load(); n > 0 {
+		t.Fatalf("handler ran concurrently %d times", n)
+	}
+}
+
+// TestManagerReconcile checks that an order which ended while disconnected is delivered, although it is no longer
+// open nor created since the disconnect.
+func TestManagerReconcile(t *testing.T) {
+	source := newFakeSource()
+	var events []AccountEvent
+	tracker := newAccountTracker(func(event AccountEvent) { events = append(events, event) })
+	manager := NewManager("", ManagerConfig{Reconciler: source})
+	manager.account = tracker
+
+	placed := testOrder("o1", models.Open, 0)
+	placed.CreatedAt = time.Now().Add(-time.Hour)
+	tracker.deliver(OrderEvent{Order: placed})
+
+	disconnectedAt := time.Now()
+	filled := testOrder("o1", models.FullyFilled, 1)
+	filled.CreatedAt = placed.CreatedAt
+	source.set(filled)
+	source.fills = []models.ApiFill{{FillID: "f1", OrderID: "o1", CreatedAt: time.Now()}}
+
+	if err := manager.reconcile(context.Background(), disconnectedAt); err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want placed, filled and the fill: %+v", len(events), events)
+	}
+	if e, ok := events[1].(OrderEvent); !ok || e.Order.State != models.FullyFilled {
+		t.Fatalf("second event %+v, want the order filled", events[1])
+	}
+	if e, ok := events[2].(FillEvent); !ok || e.Fill.FillID != "f1" {
+		t.Fatalf("third event %+v, want fill f1", events[2])
+	}
+	if live := tracker.liveOrders(); len(live) != 0 {
+		t.Fatalf("live orders %v after the order ended", live)
+	}
+
+	// A second reconciliation delivers nothing new
+	if err := manager.reconcile(context.Background(), disconnectedAt); err != nil {
+		t.Fatalf("second reconcile: %v", err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("got %d events after a second reconcile, want 3", len(events))
+	}
+}
@@ -0,0 +1,241 @@
+package wsclient
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Enclave-Markets/enclave-go/models"
+)
+
+// OrderSource is polled by a Poller. *apiclient.ApiClient implements it.
+type OrderSource interface {
+	GetSpotOrderCtx(ctx context.Context, order models.OrderRef) (*models.GenericResponse[models.ApiOrder], error)
+	ListSpotOrdersCtx(ctx context.Context, params models.OrderParams) (*models.V1PageRes[models.ApiOrder], error)
+	GetSpotFillsCtx(ctx context.Context, params models.FillParams) (*models.V1PageRes[models.ApiFill], error)
+}
+
+type PollerConfig struct {
+	// Markets to poll. Empty polls every market.
+	Markets []models.Market
+
+	// Polling interval while the account has open orders. Defaults to 1 second.
+	ActiveInterval time.Duration
+
+	// Polling interval while the account has no open order. Defaults to 10 seconds.
+	IdleInterval time.Duration
+
+	// Called from the poller goroutine when a poll fails. The next poll picks up where the failed one stopped.
+	OnError func(error)
+}
+
+// Poller emits the account's order and fill events by polling REST, for when the stream is unavailable. Handlers
+// written for SubscribeAccount work unchanged: an OrderEvent is delivered when an order's state or filled size
+// changes and a FillEvent for every new fill, each at most once. Only changes after the first poll are delivered.
+//
+// A poll lists the open orders and the ones created since the last poll, and looks up the orders that left the open
+// list, so its cost follows the account's activity rather than its history.
+type Poller struct {
+	source  OrderSource
+	config  PollerConfig
+	tracker *accountTracker
+
+	// when the last orders poll completed, zero until the orders are seeded
+	ordersPolledAt time.Time
+	fillsSeeded    bool
+}
+
+func NewPoller(source OrderSource, config PollerConfig, handler func(AccountEvent)) *Poller {
+	if config.ActiveInterval <= 0 {
+		config.ActiveInterval = time.Second
+	}
+	if config.IdleInterval <= 0 {
+		config.IdleInterval = 10 * time.Second
+	}
+
+	return &Poller{
+		source:  source,
+		config:  config,
+		tracker: newAccountTracker(handler),
+	}
+}
+
+// Run polls until ctx is done and returns the context error. It polls at ActiveInterval while orders are open and at
+// IdleInterval otherwise.
+func (p *Poller) Run(ctx context.Context) error {
+	for {
+		interval := p.config.IdleInterval
+		open, err := p.Poll(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if p.config.OnError != nil {
+				p.config.OnError(err)
+			}
+		}
+		if open {
+			interval = p.config.ActiveInterval
+		}
+
+		if err := sleep(ctx, interval); err != nil {
+			return err
+		}
+	}
+}
+
+// Poll fetches the orders and the new fills once, delivers the changes and reports whether any order is open. The
+// orders and the fills are each seeded without delivering anything by their first successful poll. Poll must not be
+// called concurrently with Run.
+func (p *Poller) Poll(ctx context.Context) (bool, error) {
+	open, err := p.pollOrders(ctx)
+	if err != nil {
+		return open, err
+	}
+	if err := p.pollFills(ctx); err != nil {
+		return open, err
+	}
+	return open, nil
+}
+
+func (p *Poller) pollOrders(ctx context.Context) (bool, error) {
+	seeding := p.ordersPolledAt.IsZero()
+	// Unknown orders that ended before the last poll were delivered then, or ended before the poller started
+	since := p.ordersPolledAt.Add(-dedupWindow)
+	deliver := func(order models.ApiOrder) {
+		if seeding {
+			p.tracker.observe(OrderEvent{Order: order}, time.Time{})
+		} else {
+			p.tracker.deliverSince(OrderEvent{Order: order}, since)
+		}
+	}
+
+	createdSince := &since
+	if seeding {
+		createdSince = nil
+	}
+	open, err := syncOrders(ctx, p.source, p.tracker, p.markets(), createdSince, deliver)
+	if err != nil {
+		return open, err
+	}
+
+	p.ordersPolledAt = time.Now()
+	return open, nil
+}
+
+// syncOrders hands deliver the open orders of markets, the orders created since createdSince unless it is nil, and
+// the orders still live for tracker that left the open list, so the orders that ended in between are seen too. It
+// reports whether any order is open.
+func syncOrders(
+	ctx context.Context,
+	source OrderSource,
+	tracker *accountTracker,
+	markets []models.Market,
+	createdSince *time.Time,
+	deliver func(models.ApiOrder),
+) (bool, error) {
+	openState := models.Open
+	open := map[models.OrderID]bool{}
+	for _, market := range markets {
+		orders, err := listOrders(ctx, source, models.OrderParams{Status: &openState, Market: market})
+		if err != nil {
+			return false, fmt.Errorf("failed to list open orders: %w", err)
+		}
+		for _, order := range orders {
+			open[order.OrderID] = true
+			deliver(order)
+		}
+
+		if createdSince == nil {
+			continue
+		}
+		orders, err = listOrders(ctx, source, models.OrderParams{Market: market, StartTime: createdSince})
+		if err != nil {
+			return len(open) > 0, fmt.Errorf("failed to list recent orders: %w", err)
+		}
+		for _, order := range orders {
+			deliver(order)
+		}
+	}
+
+	for _, orderId := range tracker.liveOrders() {
+		if open[orderId] {
+			continue
+		}
+		res, err := source.GetSpotOrderCtx(ctx, orderId)
+		if err != nil {
+			return len(open) > 0, fmt.Errorf("failed to get order %s: %w", orderId, err)
+		}
+		deliver(res.Result)
+	}
+
+	return len(open) > 0, nil
+}
+
+// listOrders returns every page of the orders matching params.
+func listOrders(ctx context.Context, source OrderSource, params models.OrderParams) ([]models.ApiOrder, error) {
+	var orders []models.ApiOrder
+	for page := 0; page < maxReconcilePages; page++ {
+		res, err := source.ListSpotOrdersCtx(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		for _, order := range res.Result {
+			if order != nil {
+				orders = append(orders, *order)
+			}
+		}
+		if res.PageInfo.NextCursor == "" || len(res.Result) == 0 {
+			break
+		}
+		params.Cursor = res.PageInfo.NextCursor
+	}
+	return orders, nil
+}
+
+func (p *Poller) pollFills(ctx context.Context) error {
+	deliver := p.tracker.deliver
+	if !p.fillsSeeded {
+		deliver = func(event AccountEvent) { p.tracker.observe(event, time.Time{}) }
+	}
+
+	// Every poll starts from the last fill seen rather than from a saved cursor, which would only be right if fills
+	// paged oldest first. The fills of the overlap are known to the tracker.
+	since := p.tracker.fillsSince()
+	params := models.FillParams{StartTime: &since}
+	if len(p.config.Markets) == 1 {
+		params.Market = string(p.config.Markets[0])
+	}
+
+	for page := 0; page < maxReconcilePages; page++ {
+		fills, err := p.source.GetSpotFillsCtx(ctx, params)
+		if err != nil {
+			return fmt.Errorf("failed to poll fills: %w", err)
+		}
+		for _, fill := range fills.Result {
+			if p.polled(fill.Market) {
+				deliver(FillEvent{Fill: *fill})
+			}
+		}
+		if fills.PageInfo.NextCursor == "" || len(fills.Result) == 0 {
+			break
+		}
+		params.Cursor = fills.PageInfo.NextCursor
+	}
+
+	p.fillsSeeded = true
+	return nil
+}
+
+// markets returns the markets to list orders of, a single empty market for every market.
+func (p *Poller) markets() []models.Market {
+	if len(p.config.Markets) == 0 {
+		return []models.Market{""}
+	}
+	return p.config.Markets
+}
+
+func (p *Poller) polled(market models.Market) bool {
+	return len(p.config.Markets) == 0 || slices.Contains(p.config.Markets, market)
+}
@@ -0,0 +1,73 @@
+package wsclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Enclave-Markets/enclave-go/models"
+)
+
+func TestPoller(t *testing.T) {
+	source := newFakeSource()
+	source.fillPage = 1
+	var events []AccountEvent
+	poller := NewPoller(source, PollerConfig{}, func(event AccountEvent) { events = append(events, event) })
+	ctx := context.Background()
+
+	// The first poll seeds without delivering
+	resting := testOrder("o1", models.Open, 0)
+	resting.CreatedAt = time.Now().Add(-time.Hour)
+	source.set(resting)
+	source.fills = []models.ApiFill{{FillID: "f0", CreatedAt: time.Now().Add(-time.Hour)}}
+	open, err := poller.Poll(ctx)
+	if err != nil || !open {
+		t.Fatalf("seeding poll: open=%v err=%v", open, err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("seeding poll delivered %+v", events)
+	}
+
+	// The resting order is filled and leaves the open list, a new order is placed
+	filled := testOrder("o1", models.FullyFilled, 1)
+	filled.CreatedAt = resting.CreatedAt
+	source.set(filled)
+	source.set(testOrder("o2", models.Open, 0))
+	source.fills = append(source.fills,
+		models.ApiFill{FillID: "f1", OrderID: "o1", CreatedAt: time.Now()},
+		models.ApiFill{FillID: "f2", OrderID: "o1", CreatedAt: time.Now()},
+	)
+
+	if _, err := poller.Poll(ctx); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	var orders []models.OrderID
+	var fills []models.FillID
+	for _, event := range events {
+		switch e := event.(type) {
+		case OrderEvent:
+			orders = append(orders, e.Order.OrderID)
+		case FillEvent:
+			fills = append(fills, e.Fill.FillID)
+		}
+	}
+	if len(orders) != 2 || len(fills) != 2 {
+		t.Fatalf("got orders %v and fills %v, want o1 and o2 and fills f1 and f2", orders, fills)
+	}
+
+	// Nothing changed, nothing is delivered twice
+	delivered := len(events)
+	if _, err := poller.Poll(ctx); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if len(events) != delivered {
+		t.Fatalf("unchanged poll delivered %+v", events[delivered:])
+	}
+
+	// Every fills poll starts from a time, never from a cursor kept from an earlier poll
+	for _, params := range source.fillParams {
+		if params.StartTime == nil {
+			t.Fatalf("fills polled without a start time: %+v", params)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package wsclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Enclave-Markets/enclave-go/models"
+)
+
+// Private channels, available after Login.
+const (
+	ChannelOrders = "orders"
+	ChannelFills  = "fills"
+)
+
+// Authenticator signs the stream login. *apiclient.ApiClient implements it, so the stream uses the same API key,
+// signer and clock offset as REST requests.
+type Authenticator interface {
+	AuthHeadersCtx(ctx context.Context, httpVerb string, path string, request any) (map[string]string, error)
+}
+
+type loginArgs struct {
+	Key  string `json:"key"`
+	Time string `json:"time"`
+	Sign string `json:"sign"`
+}
+
+// AccountEvent is an event of the private stream, either an OrderEvent or a FillEvent.
+type AccountEvent interface {
+	isAccountEvent()
+}
+
+// OrderEvent reports a new state of one of the account's orders, e.g. Open, FullyFilled, or Canceled along with its
+// CancelReason.
+type OrderEvent struct {
+	Order models.ApiOrder
+}
+
+// FillEvent reports a new fill of one of the account's orders.
+type FillEvent struct {
+	Fill models.ApiFill
+}
+
+func (OrderEvent) isAccountEvent() {}
+func (FillEvent) isAccountEvent()  {}
+
+// Login authenticates the connection with the ENCLAVE-KEY-ID, ENCLAVE-TIMESTAMP and ENCLAVE-SIGN values of a signed
+// GET of the stream path, and waits for the server to accept them.
+func (c *Client) Login(ctx context.Context, auth Authenticator) error {
+	headers, err := auth.AuthHeadersCtx(ctx, http.MethodGet, models.WebsocketPath, nil)
+	if err != nil {
+		return fmt.Errorf("failed to sign stream login: %w", err)
+	}
+	if headers["ENCLAVE-KEY-ID"] == "" {
+		return fmt.Errorf("stream login requires an api key")
+	}
+
+	result := make(chan error, 1)
+	c.mu.Lock()
+	c.loginResult = result
+	c.mu.Unlock()
+
+	err = c.send(ctx, request{
+		Op: "login",
+		Args: loginArgs{
+			Key:  headers["ENCLAVE-KEY-ID"],
+			Time: headers["ENCLAVE-TIMESTAMP"],
+			Sign: headers["ENCLAVE-SIGN"],
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	select {
+	case err := <-result:
+		return err
+	case <-c.done:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SubscribeAccount streams the account's order state changes and fills as AccountEvent values. It requires Login.
+//
+//	err := stream.SubscribeAccount(ctx, func(event wsclient.AccountEvent) {
+//		switch e := event.(type) {
+//		case wsclient.OrderEvent:
+//			...
+//		case wsclient.FillEvent:
+//			...
+//		}
+//	})
+func (c *Client) SubscribeAccount(ctx context.Context, handler func(AccountEvent)) error {
+	err := subscribe(ctx, c, ChannelOrders, "", func(order models.ApiOrder) {
+		handler(OrderEvent{Order: order})
+	})
+	if err != nil {
+		return err
+	}
+
+	return subscribe(ctx, c, ChannelFills, "", func(fill models.ApiFill) {
+		handler(FillEvent{Fill: fill})
+	})
+}